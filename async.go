@@ -0,0 +1,196 @@
+package log
+
+import "fmt"
+import "sync"
+
+// A FullPolicy says what an AsyncLogger does with a line when its
+// queue is full.
+type FullPolicy int
+
+const (
+	// BlockWhenFull makes the logging call wait for room in the
+	// queue, so no line is lost.
+	BlockWhenFull FullPolicy = iota
+	// DropWhenFull drops the line, so the logging call never waits.
+	DropWhenFull
+)
+
+// An AsyncLogger hands lines to a background goroutine that writes
+// them to another Logger, so that logging calls don't wait on the
+// write. Lines are formatted before they're queued, so later changes
+// to the arguments don't show. Since the wrapped logger is called
+// from the background goroutine, caller lookup can't find the code
+// that logged.
+type AsyncLogger struct {
+	l Logger
+	q *asyncQueue
+}
+
+// asyncQueue is the queue shared by a tree of AsyncLoggers.
+type asyncQueue struct {
+	mu     sync.RWMutex
+	closed bool
+	ch     chan asyncEntry
+	done   chan struct{}
+	policy FullPolicy
+	stats  *stats
+}
+
+// asyncEntry is a queued line, or if flushed is set, a marker that
+// is closed once every line queued before it has been written.
+type asyncEntry struct {
+	l       Logger
+	level   Level
+	msg     string
+	flushed chan struct{}
+}
+
+// Async returns a logger that queues up to size lines for a
+// background goroutine to write to l, applying policy when the queue
+// is full. Call Close to write out the queue and stop the goroutine.
+// Panic and Fatal wait for the queue to be written, and then call l
+// directly. Loggers derived via Prefix and With share the queue.
+func Async(l Logger, size int, policy FullPolicy) *AsyncLogger {
+	q := &asyncQueue{
+		ch:     make(chan asyncEntry, size),
+		done:   make(chan struct{}),
+		policy: policy,
+		stats:  &stats{},
+	}
+	go q.run()
+	return &AsyncLogger{l: l, q: q}
+}
+
+func (q *asyncQueue) run() {
+	defer close(q.done)
+	for e := range q.ch {
+		if e.flushed != nil {
+			close(e.flushed)
+			continue
+		}
+		e.l.Log(e.level, "%s", e.msg)
+	}
+}
+
+// enqueue queues e per the policy, and reports whether it was
+// queued. Nothing is queued once the queue is closed.
+func (q *asyncQueue) enqueue(e asyncEntry, block bool) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return false
+	}
+	if block {
+		q.ch <- e
+		return true
+	}
+	select {
+	case q.ch <- e:
+		return true
+	default:
+		return false
+	}
+}
+
+// Flush waits until every line queued so far has been written.
+func (a *AsyncLogger) Flush() {
+	flushed := make(chan struct{})
+	if a.q.enqueue(asyncEntry{flushed: flushed}, true) {
+		<-flushed
+	}
+}
+
+// Close writes out the queue and stops the background goroutine.
+// Lines logged afterwards are dropped.
+func (a *AsyncLogger) Close() error {
+	a.q.mu.Lock()
+	if !a.q.closed {
+		a.q.closed = true
+		close(a.q.ch)
+	}
+	a.q.mu.Unlock()
+	<-a.q.done
+	return nil
+}
+
+// Stats returns the number of lines queued and dropped so far by a
+// and the loggers derived from it.
+func (a *AsyncLogger) Stats() Stats {
+	return a.q.stats.snapshot()
+}
+
+// Log queues the line for the background goroutine. At PanicLevel
+// and above it instead waits for the queue to be written, and then
+// calls the wrapped logger directly, so that the line is written
+// before the panic unwinds or the process exits.
+func (a *AsyncLogger) Log(level Level, format string, v ...interface{}) {
+	if level >= PanicLevel {
+		a.q.stats.count(level, true)
+		a.Flush()
+		a.l.Log(level, format, v...)
+		return
+	}
+	if !a.l.Enabled(level) {
+		return
+	}
+	e := asyncEntry{l: a.l, level: level, msg: fmt.Sprintf(format, v...)}
+	a.q.stats.count(level, a.q.enqueue(e, a.q.policy == BlockWhenFull))
+}
+
+// Debug queues the line for the background goroutine.
+func (a *AsyncLogger) Debug(format string, v ...interface{}) {
+	a.Log(DebugLevel, format, v...)
+}
+
+// Info queues the line for the background goroutine.
+func (a *AsyncLogger) Info(format string, v ...interface{}) {
+	a.Log(InfoLevel, format, v...)
+}
+
+// Warning queues the line for the background goroutine.
+func (a *AsyncLogger) Warning(format string, v ...interface{}) {
+	a.Log(WarningLevel, format, v...)
+}
+
+// Error queues the line for the background goroutine.
+func (a *AsyncLogger) Error(format string, v ...interface{}) {
+	a.Log(ErrorLevel, format, v...)
+}
+
+// Panic waits for the queue to be written, and then forwards to the
+// wrapped logger.
+func (a *AsyncLogger) Panic(format string, v ...interface{}) {
+	a.Log(PanicLevel, format, v...)
+}
+
+// Fatal waits for the queue to be written, and then forwards to the
+// wrapped logger.
+func (a *AsyncLogger) Fatal(format string, v ...interface{}) {
+	a.Log(FatalLevel, format, v...)
+}
+
+// Enabled reports whether the wrapped logger is enabled for level.
+func (a *AsyncLogger) Enabled(level Level) bool {
+	return a.l.Enabled(level)
+}
+
+// Must calls a.Panic() if err is not nil, and otherwise forwards to
+// the wrapped logger.
+func (a *AsyncLogger) Must(message string, err error) {
+	if err != nil {
+		a.Panic("Failed to %s: %v", message, err)
+	}
+	a.l.Must(message, nil)
+}
+
+// Prefix returns an asynchronous logger around the wrapped logger's
+// Prefix, sharing a's queue.
+func (a *AsyncLogger) Prefix(prefix string) Logger {
+	return &AsyncLogger{l: a.l.Prefix(prefix), q: a.q}
+}
+
+// With returns an asynchronous logger around the wrapped logger's
+// With, sharing a's queue.
+func (a *AsyncLogger) With(key string, value interface{}) Logger {
+	return &AsyncLogger{l: a.l.With(key, value), q: a.q}
+}
@@ -0,0 +1,116 @@
+package log
+
+import "sync"
+import "time"
+import "testing"
+import "strings"
+
+// gateWriter holds up every write until released, signalling started
+// when the first write arrives.
+type gateWriter struct {
+	CaptureBuffer
+	once     sync.Once
+	started  chan struct{}
+	released chan struct{}
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{started: make(chan struct{}), released: make(chan struct{})}
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.released
+	return w.CaptureBuffer.Write(p)
+}
+
+// newAsync returns an AsyncLogger around a text logger writing to a
+// gateWriter, with its background goroutine stuck writing line 0, so
+// that the queue is empty.
+func newAsync(t *testing.T, size int, policy FullPolicy) (*AsyncLogger, *gateWriter) {
+	t.Helper()
+	w := newGateWriter()
+	l := NewWriter(w)
+	l.SetTimeFormat("-")
+	a := Async(l, size, policy)
+	a.Info("0")
+	<-w.started
+	return a, w
+}
+
+// messages returns the last column of each line written to w.
+func (w *gateWriter) messages() []string {
+	var msgs []string
+	for _, line := range w.Lines() {
+		msgs = append(msgs, line[strings.LastIndex(line, "\t")+1:])
+	}
+	return msgs
+}
+
+func TestAsyncDropWhenFull(t *testing.T) {
+	a, w := newAsync(t, 2, DropWhenFull)
+	for _, msg := range []string{"1", "2", "3", "4"} {
+		a.Info("%s", msg)
+	}
+	close(w.released)
+	a.Close()
+
+	if got := strings.Join(w.messages(), " "); got != "0 1 2" {
+		t.Errorf("wrote %q, want 0 1 2", got)
+	}
+	if st := a.Stats(); st.Written[InfoLevel] != 3 || st.Dropped[InfoLevel] != 2 {
+		t.Errorf("got stats %+v", st)
+	}
+}
+
+func TestAsyncBlockWhenFull(t *testing.T) {
+	a, w := newAsync(t, 1, BlockWhenFull)
+	a.Info("1")
+	done := make(chan struct{})
+	go func() {
+		a.Prefix("p").Info("2")
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("logging to a full queue didn't wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(w.released)
+	<-done
+	a.Close()
+
+	if got := strings.Join(w.messages(), " "); got != "0 1 2" {
+		t.Errorf("wrote %q, want 0 1 2", got)
+	}
+	if st := a.Stats(); st.Written[InfoLevel] != 3 || st.Dropped[InfoLevel] != 0 {
+		t.Errorf("got stats %+v", st)
+	}
+}
+
+func TestAsyncPanicWritesQueue(t *testing.T) {
+	a, w := newAsync(t, 4, BlockWhenFull)
+	a.Info("1")
+	close(w.released)
+	if v := catchPanic(func() { a.Panic("boom") }); v != "boom" {
+		t.Errorf("panicked with %v, want boom", v)
+	}
+	// the panic line is written synchronously, after the queue
+	if got := strings.Join(w.messages(), " "); got != "0 1 boom" {
+		t.Errorf("wrote %q, want 0 1 boom", got)
+	}
+	a.Close()
+}
+
+func TestAsyncClose(t *testing.T) {
+	a, w := newAsync(t, 4, BlockWhenFull)
+	a.Info("1")
+	close(w.released)
+	a.Close()
+	a.Info("after")
+	a.Close()
+	a.Flush()
+	if got := strings.Join(w.messages(), " "); got != "0 1" {
+		t.Errorf("wrote %q, want 0 1", got)
+	}
+}
@@ -0,0 +1,146 @@
+package log
+
+import "io"
+import "sync"
+import "time"
+
+// A BatchWriter collects lines and writes them to another writer
+// together, in one call, once a batch is full or a flush interval
+// has passed, whichever comes first. Under bursty load that saves a
+// syscall per line, while the interval bounds how long a quiet
+// logger's lines wait. It is safe for concurrent use.
+//
+// A DefaultLogger writing to a BatchWriter flushes it, through Sync,
+// before panicking or exiting. Lines still waiting when the process
+// exits any other way are lost, so Close it on the way out.
+type BatchWriter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	size  int
+	batch []byte
+	lines int
+	err   error
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewBatchWriter returns a writer that writes to w in batches of
+// size lines, and at least every interval while lines are waiting.
+// An interval of zero or less disables the time-based flush, so that
+// only full batches, Flush and Close write to w.
+func NewBatchWriter(w io.Writer, size int, interval time.Duration) *BatchWriter {
+	if interval <= 0 {
+		return newBatchWriter(w, size, nil, nil)
+	}
+	t := time.NewTicker(interval)
+	return newBatchWriter(w, size, t.C, t.Stop)
+}
+
+// newBatchWriter returns a BatchWriter that flushes whenever tick
+// delivers, unless tick is nil, and calls stopTick once it stops
+// listening, so that tests can drive the time-based flush.
+func newBatchWriter(w io.Writer, size int, tick <-chan time.Time, stopTick func()) *BatchWriter {
+	b := &BatchWriter{w: w, size: size}
+	if tick != nil {
+		b.stop = make(chan struct{})
+		b.done = make(chan struct{})
+		go b.run(tick, stopTick)
+	}
+	return b
+}
+
+func (b *BatchWriter) run(tick <-chan time.Time, stopTick func()) {
+	defer close(b.done)
+	defer stopTick()
+	for {
+		select {
+		case <-tick:
+			b.mu.Lock()
+			if err := b.flush(); err != nil {
+				// nobody to return it to, so keep it for the next call
+				b.err = err
+			}
+			b.mu.Unlock()
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// Write adds p to the batch as a line, writing the batch out if that
+// fills it. A write error from a background flush is returned by the
+// next call to Write, Flush or Close.
+func (b *BatchWriter) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.takeErr(); err != nil {
+		return 0, err
+	}
+	b.batch = append(b.batch, p...)
+	b.lines++
+	if b.lines >= b.size {
+		if err := b.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes out any waiting lines.
+func (b *BatchWriter) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.takeErr(); err != nil {
+		return err
+	}
+	return b.flush()
+}
+
+// flush writes the batch to w. The caller must hold b.mu.
+func (b *BatchWriter) flush() error {
+	if len(b.batch) == 0 {
+		return nil
+	}
+	_, err := b.w.Write(b.batch)
+	b.batch = b.batch[:0]
+	b.lines = 0
+	return err
+}
+
+// takeErr returns and clears the kept error. The caller must hold
+// b.mu.
+func (b *BatchWriter) takeErr() error {
+	err := b.err
+	b.err = nil
+	return err
+}
+
+// Sync flushes, and then syncs the underlying writer to storage if
+// it can be.
+func (b *BatchWriter) Sync() error {
+	if err := b.Flush(); err != nil {
+		return err
+	}
+	if s, ok := b.w.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Close stops the time-based flush, writes out the final batch, and
+// then closes the underlying writer if it is an io.Closer.
+func (b *BatchWriter) Close() error {
+	if b.stop != nil {
+		b.once.Do(func() { close(b.stop) })
+		<-b.done
+	}
+	err := b.Flush()
+	if c, ok := b.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+package log
+
+import "time"
+import "errors"
+import "testing"
+import "strings"
+
+// newTestBatchWriter returns a BatchWriter whose time-based flush is
+// driven by tick, and a function that ticks and waits for the flush.
+func newTestBatchWriter(t *testing.T, size int) (*BatchWriter, *CaptureBuffer, func()) {
+	t.Helper()
+	under := &CaptureBuffer{}
+	tick, flush := newManualTick()
+	b := newBatchWriter(under, size, tick, func() {})
+	t.Cleanup(func() { b.Close() })
+	return b, under, flush
+}
+
+func TestBatchWriterSize(t *testing.T) {
+	b, under, _ := newTestBatchWriter(t, 3)
+	b.Write([]byte("one\n"))
+	b.Write([]byte("two\n"))
+	if under.String() != "" {
+		t.Fatalf("got %q before the batch was full", under.String())
+	}
+	b.Write([]byte("three\n"))
+	if got := under.String(); got != "one\ntwo\nthree\n" {
+		t.Errorf("got %q, want the full batch", got)
+	}
+}
+
+func TestBatchWriterInterval(t *testing.T) {
+	b, under, tick := newTestBatchWriter(t, 100)
+	b.Write([]byte("one\n"))
+	b.Write([]byte("two\n"))
+	if under.String() != "" {
+		t.Fatalf("got %q before the tick", under.String())
+	}
+	tick()
+	if got := under.String(); got != "one\ntwo\n" {
+		t.Errorf("got %q after the tick, want both lines", got)
+	}
+	// nothing waiting, nothing written
+	tick()
+	b.Write([]byte("three\n"))
+	tick()
+	if got := under.String(); got != "one\ntwo\nthree\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestBatchWriterBackgroundError(t *testing.T) {
+	errBroken := errors.New("broken pipe")
+	tick, flush := newManualTick()
+	b := newBatchWriter(errWriter{errBroken}, 100, tick, func() {})
+	defer b.Close()
+	b.Write([]byte("one\n"))
+	flush()
+	// the error from the background flush comes back from the next
+	// call, once
+	if _, err := b.Write([]byte("two\n")); err != errBroken {
+		t.Errorf("got %v, want errBroken", err)
+	}
+	if _, err := b.Write([]byte("three\n")); err != nil {
+		t.Errorf("got %v again", err)
+	}
+}
+
+func TestBatchWriterClose(t *testing.T) {
+	under := &closeRecorder{}
+	stopped := false
+	b := newBatchWriter(under, 100, make(chan time.Time), func() { stopped = true })
+	b.Write([]byte("one\n"))
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if under.String() != "one\n" || under.closed != 1 || !stopped {
+		t.Errorf("got %q, closed %d times, ticker stopped %v", under.String(), under.closed, stopped)
+	}
+}
+
+func TestBatchWriterPanicFlushes(t *testing.T) {
+	b, under, _ := newTestBatchWriter(t, 100)
+	l := NewWriter(b)
+	l.SetClock(FixedClock(testTime))
+	l.Info("before")
+	catchPanic(func() { l.Panic("boom") })
+	if got := under.String(); !strings.HasSuffix(got, "\tbefore\n2006-01-02T15:04:05Z\tPANIC\t\tboom\n") {
+		t.Errorf("got %q, want both lines flushed", got)
+	}
+}
+
+func TestNewBatchWriterNoInterval(t *testing.T) {
+	under := &CaptureBuffer{}
+	b := NewBatchWriter(under, 2, 0)
+	b.Write([]byte("one\n"))
+	b.Flush()
+	if under.String() != "one\n" {
+		t.Errorf("got %q after Flush", under.String())
+	}
+	b.Close()
+}
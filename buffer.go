@@ -0,0 +1,115 @@
+package log
+
+import "io"
+import "sync"
+import "time"
+import "bufio"
+
+// A buffer holds the lines of a buffered logger until they are
+// flushed to the underlying writer.
+type buffer struct {
+	*bufio.Writer
+	under io.Writer
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewBuffered returns a logger that collects lines in a buffer of
+// size bytes in front of w, saving a write to w per line. Lines
+// reach w when the buffer fills, when Flush or Close is called, and
+// before Panic and Fatal. Loggers derived via Prefix share the
+// buffer.
+func NewBuffered(w io.Writer, size int) (l *DefaultLogger) {
+	b := &buffer{
+		Writer: bufio.NewWriterSize(w, size),
+		under:  w,
+	}
+	l = NewWriter(b)
+	l.buf = b
+	l.flushLevel = neverFlush
+	return
+}
+
+// neverFlush is the flush level of a buffered logger that only
+// flushes when it must.
+const neverFlush = FatalLevel + 1
+
+// SetFlushLevel makes a buffered logger flush after writing any line
+// at level or above, so that say warnings reach the writer at once
+// while info lines stay buffered for throughput. By default only
+// Panic and Fatal flush. Loggers derived via Prefix inherit the
+// setting.
+func (l *DefaultLogger) SetFlushLevel(level Level) {
+	l.flushLevel = level
+}
+
+// buffer returns the buffer l writes into: its own, or for a clone
+// of a buffered logger, that of the logger it was cloned from, until
+// the clone's output is changed. It returns nil if l isn't buffered.
+func (l *DefaultLogger) buffer() *buffer {
+	if l.buf != nil {
+		return l.buf
+	}
+	b, _ := l.writer().(*buffer)
+	return b
+}
+
+// Flush writes any buffered lines to the underlying writer. It does
+// nothing for a logger that isn't buffered.
+func (l *DefaultLogger) Flush() error {
+	b := l.buffer()
+	if b == nil {
+		return nil
+	}
+	mu := l.mutex()
+	mu.Lock()
+	defer mu.Unlock()
+	return b.Flush()
+}
+
+// FlushEvery starts a goroutine that flushes a buffered logger every
+// interval, so that lines don't sit in the buffer indefinitely. Call
+// Close to stop it. FlushEvery does nothing if l isn't buffered or
+// is already flushing on an interval.
+func (l *DefaultLogger) FlushEvery(interval time.Duration) {
+	b := l.buf
+	if b == nil || b.stop != nil {
+		return
+	}
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+
+	go func() {
+		defer close(b.done)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				l.Flush()
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopFlushing stops any interval flushing started by FlushEvery,
+// and waits for it to finish.
+func (b *buffer) stopFlushing() {
+	if b.stop != nil {
+		b.once.Do(func() { close(b.stop) })
+		<-b.done
+	}
+}
+
+// reset flushes the buffer and then points it at w instead.
+func (b *buffer) reset(mu *sync.Mutex, w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	b.Flush()
+	b.Writer.Reset(w)
+	b.under = w
+}
@@ -0,0 +1,108 @@
+package log
+
+import "os"
+import "time"
+import "testing"
+import "path/filepath"
+
+func TestBufferedFlush(t *testing.T) {
+	buf := &CaptureBuffer{}
+	l := NewBuffered(buf, 4096)
+	l.SetClock(FixedClock(testTime))
+
+	l.Info("hello")
+	if got := buf.String(); got != "" {
+		t.Fatalf("got %q before Flush, want nothing", got)
+	}
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "2006-01-02T15:04:05Z\tINFO\t\thello\n"; buf.String() != want {
+		t.Errorf("got %q after Flush, want %q", buf.String(), want)
+	}
+}
+
+func TestBufferedPanicFlushes(t *testing.T) {
+	buf := &CaptureBuffer{}
+	l := NewBuffered(buf, 4096)
+	l.SetClock(FixedClock(testTime))
+
+	l.Info("before")
+	catchPanic(func() { l.Prefix("p").Panic("boom") })
+	want := "2006-01-02T15:04:05Z\tINFO\t\tbefore\n" +
+		"2006-01-02T15:04:05Z\tPANIC\tp\tboom\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFlushLevel(t *testing.T) {
+	tests := []struct {
+		level   Level
+		flushed bool
+	}{
+		{DebugLevel, false},
+		{InfoLevel, false},
+		{WarningLevel, true},
+		{ErrorLevel, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			buf := &CaptureBuffer{}
+			l := NewBuffered(buf, 4096)
+			l.SetLevel(DebugLevel)
+			l.SetFlushLevel(WarningLevel)
+
+			l.Info("before")
+			// inherited through Prefix
+			l.Prefix("p").Log(tt.level, "hello")
+			if got := len(buf.Lines()); (got == 2) != tt.flushed || (got == 0) == tt.flushed {
+				t.Errorf("%d lines written, want flushed = %v", got, tt.flushed)
+			}
+		})
+	}
+}
+
+func TestFlushEvery(t *testing.T) {
+	buf := &CaptureBuffer{}
+	l := NewBuffered(buf, 4096)
+	l.FlushEvery(time.Millisecond)
+	defer l.Close()
+
+	l.Info("hello")
+	deadline := time.Now().Add(5 * time.Second)
+	for buf.String() == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("line never flushed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// benchmarkFile logs to a file, so that each write is a syscall.
+func benchmarkFile(b *testing.B, open func(f *os.File) *DefaultLogger) {
+	f, err := os.Create(filepath.Join(b.TempDir(), "bench.log"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+	l := open(f)
+	l.SetClock(FixedClock(testTime))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("request %d done", i)
+	}
+	b.StopTimer()
+	if err := l.Flush(); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func BenchmarkUnbuffered(b *testing.B) {
+	benchmarkFile(b, func(f *os.File) *DefaultLogger { return NewWriter(f) })
+}
+
+func BenchmarkBuffered(b *testing.B) {
+	benchmarkFile(b, func(f *os.File) *DefaultLogger { return NewBuffered(f, 64<<10) })
+}
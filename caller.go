@@ -0,0 +1,60 @@
+package log
+
+import "fmt"
+import "strings"
+import "reflect"
+import "runtime"
+import "path/filepath"
+
+// pkgPrefix is the prefix of the qualified name of every function in
+// this package, such as "github.com/ispace-charrington/log.".
+var pkgPrefix = func() string {
+	name := runtime.FuncForPC(reflect.ValueOf(Default).Pointer()).Name()
+	return name[:strings.LastIndex(name, ".")+1]
+}()
+
+// callerOutside returns the file:line of the nearest frame that
+// isn't in this package, so that the caller is right no matter how
+// many of our own methods and wrappers sit in between. Frames from
+// _test.go files count as outside the package.
+func callerOutside() string {
+	var pcs [32]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		inside := strings.HasPrefix(f.Function, pkgPrefix) &&
+			!strings.HasSuffix(f.File, "_test.go")
+		if !inside {
+			return fmt.Sprintf("%s:%d", filepath.Base(f.File), f.Line)
+		}
+		if !more {
+			return "???:0"
+		}
+	}
+}
+
+// stackOutside formats the calling goroutine's stack, like
+// runtime.Stack but starting at the nearest frame outside this
+// package, with a function line and a file:line line per frame.
+func stackOutside() string {
+	var pcs [64]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	var b strings.Builder
+	outside := false
+	for {
+		f, more := frames.Next()
+		if !outside {
+			outside = !strings.HasPrefix(f.Function, pkgPrefix) ||
+				strings.HasSuffix(f.File, "_test.go")
+		}
+		if outside {
+			fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
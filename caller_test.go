@@ -0,0 +1,100 @@
+package log
+
+import "fmt"
+import "runtime"
+import "testing"
+import "strings"
+
+func TestCaller(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l *DefaultLogger) int
+	}{
+		{"direct", func(l *DefaultLogger) int {
+			l.Info("hello")
+			return line()
+		}},
+		{"prefixed", func(l *DefaultLogger) int {
+			l.Prefix("p").Info("hello")
+			return line()
+		}},
+		{"through Log", func(l *DefaultLogger) int {
+			l.Log(WarningLevel, "hello")
+			return line()
+		}},
+		{"through Multi", func(l *DefaultLogger) int {
+			Multi(l).Info("hello")
+			return line()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			l.SetCaller(true)
+			// the call is on the line before the one reported
+			want := fmt.Sprintf("\tcaller_test.go:%d\thello", tt.log(l)-1)
+			if got := buf.String(); !strings.Contains(got, want) {
+				t.Errorf("got %q, want it to contain %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCallerOffByDefault(t *testing.T) {
+	l, buf := newLogger()
+	l.Info("hello")
+	if got := buf.String(); strings.Contains(got, ".go:") {
+		t.Errorf("got %q, want no caller", got)
+	}
+}
+
+// line returns the line it is called from.
+func line() int {
+	_, _, n, _ := runtime.Caller(1)
+	return n
+}
+
+func TestPanicStack(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func() (*DefaultLogger, *CaptureBuffer)
+		want string
+	}{
+		{"text", newLogger, "\tboom\tgithub.com/ispace-charrington/log.panicFrame\n\t"},
+		{"JSON", newJSONLogger, `"msg":"boom","stack":"github.com/ispace-charrington/log.panicFrame\n\t`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := tt.new()
+			l.SetPanicStack(true)
+			var n int
+			catchPanic(func() { panicFrame(l.Prefix("p"), &n) })
+			got := buf.String()
+			// the stack starts at the caller of Panic
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("got %q, want the stack to start with panicFrame", got)
+			}
+			if !strings.Contains(got, fmt.Sprintf("caller_test.go:%d", n)) {
+				t.Errorf("got %q, want the line Panic was called on", got)
+			}
+			if strings.Contains(got, "/log.go:") {
+				t.Errorf("got %q, want no frames inside the package", got)
+			}
+		})
+	}
+}
+
+// panicFrame calls Panic on l, having set n to the line it does so
+// on.
+func panicFrame(l Logger, n *int) {
+	*n = line() + 1
+	l.Panic("boom")
+}
+
+func TestPanicStackOffByDefault(t *testing.T) {
+	l, buf := newLogger()
+	catchPanic(func() { l.Panic("boom") })
+	if got := buf.String(); got != "2006-01-02T15:04:05Z\tPANIC\t\tboom\n" {
+		t.Errorf("got %q, want no stack", got)
+	}
+}
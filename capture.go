@@ -0,0 +1,55 @@
+package log
+
+import "sync"
+import "bytes"
+import "strings"
+
+// A CaptureBuffer holds the output of a logger made by NewBuffer, for
+// tests to compare against expected output. It is safe to read while
+// loggers are writing to it.
+type CaptureBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// NewBuffer returns a logger writing to a new, empty CaptureBuffer,
+// and the buffer. Unlike a TestLogger, which records the calls, the
+// buffer captures the formatted output, so it suits snapshot tests
+// of formatting settings. Set a Clock on the logger to make the
+// timestamps predictable.
+func NewBuffer() (*DefaultLogger, *CaptureBuffer) {
+	b := &CaptureBuffer{}
+	return NewWriter(b), b
+}
+
+// Write appends p to the buffer.
+func (b *CaptureBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// String returns everything written since the buffer was made or
+// last Reset.
+func (b *CaptureBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// Lines returns what String does, split into lines, without their
+// terminators.
+func (b *CaptureBuffer) Lines() []string {
+	s := strings.TrimSuffix(b.String(), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// Reset empties the buffer.
+func (b *CaptureBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
@@ -0,0 +1,77 @@
+package log
+
+import "sync"
+import "testing"
+import "strings"
+
+func TestCaptureBuffer(t *testing.T) {
+	tests := []struct {
+		name  string
+		log   func(l *DefaultLogger)
+		lines []string
+	}{
+		{"nothing", func(l *DefaultLogger) {}, nil},
+		{"one", func(l *DefaultLogger) { l.Info("one") }, []string{
+			"2006-01-02T15:04:05Z\tINFO\t\tone",
+		}},
+		{"several", func(l *DefaultLogger) {
+			l.Info("one")
+			l.Prefix("p").Warning("two")
+			l.Error("three")
+		}, []string{
+			"2006-01-02T15:04:05Z\tINFO\t\tone",
+			"2006-01-02T15:04:05Z\tWARN\tp\ttwo",
+			"2006-01-02T15:04:05Z\tERROR\t\tthree",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := NewBuffer()
+			l.SetClock(FixedClock(testTime))
+			tt.log(l)
+			if got := buf.Lines(); strings.Join(got, "\n") != strings.Join(tt.lines, "\n") || len(got) != len(tt.lines) {
+				t.Errorf("got lines %q, want %q", got, tt.lines)
+			}
+			want := ""
+			for _, line := range tt.lines {
+				want += line + "\n"
+			}
+			if got := buf.String(); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCaptureBufferReset(t *testing.T) {
+	l, buf := NewBuffer()
+	l.SetClock(FixedClock(testTime))
+	l.Info("one")
+	buf.Reset()
+	if buf.String() != "" || buf.Lines() != nil {
+		t.Errorf("got %q after Reset", buf.String())
+	}
+	l.Info("two")
+	if want := "2006-01-02T15:04:05Z\tINFO\t\ttwo\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCaptureBufferConcurrent(t *testing.T) {
+	l, buf := NewBuffer()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				l.Info("hello")
+				_ = buf.String()
+			}
+		}()
+	}
+	wg.Wait()
+	if n := len(buf.Lines()); n != 400 {
+		t.Errorf("got %d lines, want 400", n)
+	}
+}
@@ -0,0 +1,18 @@
+package log
+
+import "time"
+
+// A Clock tells a logger what time it is. The system clock is used
+// unless another is set, which lets tests and benchmarks supply
+// fixed or cheap timestamps.
+type Clock interface {
+	Now() time.Time
+}
+
+// FixedClock is a Clock that always reports the same time.
+type FixedClock time.Time
+
+// Now returns the fixed time.
+func (c FixedClock) Now() time.Time {
+	return time.Time(c)
+}
@@ -0,0 +1,114 @@
+package log
+
+import "sync"
+import "time"
+import "testing"
+import "strings"
+
+// testClock is a Clock that only moves when told to, starting at
+// testTime.
+type testClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{t: testTime}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+// Advance moves the clock on by d.
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// newManualTick returns a channel to pass in place of a ticker's, and
+// a func that ticks on it and returns once the tick is handled.
+func newManualTick() (chan time.Time, func()) {
+	tick := make(chan time.Time)
+	return tick, func() {
+		// the second tick can't be taken until the first is handled
+		tick <- testTime
+		tick <- testTime
+	}
+}
+
+func TestClock(t *testing.T) {
+	c := newTestClock()
+	l, buf := NewBuffer()
+	l.SetClock(c)
+	pl := l.Prefix("p")
+
+	l.Info("one")
+	c.Advance(90 * time.Second)
+	pl.Info("two")
+
+	want := []string{
+		"2006-01-02T15:04:05Z\tINFO\t\tone",
+		"2006-01-02T15:05:35Z\tINFO\tp\ttwo",
+	}
+	if got := buf.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		advance time.Duration
+		want    string
+	}{
+		{"start", 0, "[  0.000s]"},
+		{"milliseconds", 123 * time.Millisecond, "[  0.123s]"},
+		{"rounded", 1500*time.Microsecond + 400*time.Nanosecond, "[  0.002s]"},
+		{"minutes", 90*time.Second + 5*time.Millisecond, "[ 90.005s]"},
+		{"wide", 2 * time.Hour, "[7200.000s]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClock()
+			l, buf := NewBuffer()
+			l.SetClock(c)
+			l.SetRelativeTime(true)
+			pl := l.Prefix("p")
+			c.Advance(tt.advance)
+			pl.Info("hello")
+			if want := tt.want + "\tINFO\tp\thello\n"; buf.String() != want {
+				t.Errorf("got %q, want %q", buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestRelativeTimeOff(t *testing.T) {
+	c := newTestClock()
+	l, buf := NewBuffer()
+	l.SetClock(c)
+	l.SetRelativeTime(true)
+	c.Advance(time.Second)
+	l.SetRelativeTime(false)
+	l.Info("hello")
+	if want := "2006-01-02T15:04:06Z\tINFO\t\thello\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRelativeTimeJSON(t *testing.T) {
+	c := newTestClock()
+	buf := &CaptureBuffer{}
+	l := NewJSON(buf)
+	l.SetClock(c)
+	l.SetRelativeTime(true)
+	c.Advance(250 * time.Millisecond)
+	l.Info("hello")
+	if got := buf.String(); !strings.HasPrefix(got, `{"time":"[  0.250s]",`) {
+		t.Errorf("got %q, want the elapsed time", got)
+	}
+}
@@ -0,0 +1,78 @@
+package log
+
+import "io"
+import "os"
+
+// ANSI escape sequences for the level column.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiGray    = "\x1b[90m"
+	ansiBlue    = "\x1b[34m"
+	ansiYellow  = "\x1b[33m"
+	ansiRed     = "\x1b[31m"
+	ansiBoldRed = "\x1b[1;31m"
+)
+
+// SetColor enables or disables ANSI color codes around the level
+// column of text output. It is off by default so that piped output
+// stays clean. Loggers derived via Prefix inherit the setting.
+func (l *DefaultLogger) SetColor(enabled bool) {
+	l.color = enabled
+}
+
+// AutoColor enables color if l is writing to a terminal, and
+// disables it otherwise.
+func (l *DefaultLogger) AutoColor() {
+	l.color = isTerminal(l.writer())
+}
+
+// levelTag returns the level column for level, colorized if color
+// is set.
+func levelTag(level Level, color bool) string {
+	if color {
+		return colorize(level, level.String())
+	}
+	return level.String()
+}
+
+// colorize wraps s in the color for level.
+func colorize(level Level, s string) string {
+	var code string
+	switch {
+	case level >= PanicLevel:
+		code = ansiBoldRed
+	case level >= ErrorLevel:
+		code = ansiRed
+	case level >= WarningLevel:
+		code = ansiYellow
+	case level >= InfoLevel:
+		code = ansiBlue
+	default:
+		code = ansiGray
+	}
+	return code + s + ansiReset
+}
+
+// IsTerminal reports whether l is writing to a terminal, such as to
+// decide whether to show progress output. It is false for any writer
+// that isn't an *os.File.
+func (l *DefaultLogger) IsTerminal() bool {
+	return isTerminal(l.writer())
+}
+
+// isTerminal reports whether w is a file open on a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return false
+	}
+	tty := false
+	if err := rc.Control(func(fd uintptr) { tty = isTerminalFd(f, fd) }); err != nil {
+		return false
+	}
+	return tty
+}
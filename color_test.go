@@ -0,0 +1,81 @@
+package log
+
+import "os"
+import "io"
+import "testing"
+import "path/filepath"
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		level Level
+		code  string
+	}{
+		{DebugLevel, ansiGray},
+		{InfoLevel, ansiBlue},
+		{WarningLevel, ansiYellow},
+		{ErrorLevel, ansiRed},
+		{PanicLevel, ansiBoldRed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			l, buf := newLogger()
+			l.SetLevel(DebugLevel)
+			l.SetColor(true)
+			// color must carry through Prefix
+			pl := l.Prefix("p")
+			catchPanic(func() { pl.Log(tt.level, "hello") })
+			want := "2006-01-02T15:04:05Z\t" + tt.code + tt.level.String() + ansiReset + "\tp\thello\n"
+			if got := buf.String(); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestColorOffByDefault(t *testing.T) {
+	l, buf := newLogger()
+	l.Warning("hello")
+	if want := "2006-01-02T15:04:05Z\tWARN\t\thello\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestAutoColorNotTerminal(t *testing.T) {
+	l, buf := newLogger()
+	l.SetColor(true)
+	l.AutoColor()
+	l.Warning("hello")
+	if want := "2006-01-02T15:04:05Z\tWARN\t\thello\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	tests := []struct {
+		name string
+		w    io.Writer
+	}{
+		{"buffer", &CaptureBuffer{}},
+		{"discard", io.Discard},
+		{"regular file", f},
+		{"pipe", w},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if NewWriter(tt.w).IsTerminal() {
+				t.Error("got true, want false")
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package log
+
+import "strings"
+
+// A Column is one of the columns of a text line, for SetColumns.
+type Column int
+
+// The columns of a text line.
+const (
+	TimeColumn Column = iota
+	LevelColumn
+	PrefixColumn
+	CallerColumn
+	MessageColumn
+	FieldsColumn
+)
+
+// SetColumns sets exactly which columns text lines have, and in what
+// order, so that a parser can rely on their positions. Columns left
+// out aren't written. A column included but empty for a line, such
+// as the prefix of an unprefixed logger, is still written, empty, so
+// the positions never shift; the caller column is only filled in if
+// SetCaller is on. The time column is left out entirely, though, if
+// SetTimestamp is off. FieldsColumn holds the fields as key=value
+// columns of their own, and so belongs last. No columns restores the
+// default layout. Loggers derived via Prefix inherit the setting.
+func (l *DefaultLogger) SetColumns(columns []Column) {
+	l.columns = append([]Column(nil), columns...)
+}
+
+// columnLine renders e with the given columns, and the level wrapped
+// in ANSI color codes if color is set.
+func columnLine(e entry, color bool, columns []Column) string {
+	parts := make([]string, 0, len(columns))
+	for _, c := range columns {
+		switch c {
+		case TimeColumn:
+			if e.time != "" {
+				parts = append(parts, e.time)
+			}
+		case LevelColumn:
+			parts = append(parts, levelTag(e.level, color))
+		case PrefixColumn:
+			if !e.omitPrefix || e.prefix != "" {
+				parts = append(parts, e.prefix)
+			}
+		case CallerColumn:
+			parts = append(parts, e.caller)
+		case MessageColumn:
+			parts = append(parts, e.msg)
+		case FieldsColumn:
+			parts = append(parts, strings.TrimPrefix(textFields(e.fields), "\t"))
+		}
+	}
+	return strings.Join(parts, "\t")
+}
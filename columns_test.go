@@ -0,0 +1,49 @@
+package log
+
+import "strconv"
+import "testing"
+import "strings"
+
+func TestColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []Column
+		caller  bool
+		want    string
+	}{
+		{"default", nil, false, "2006-01-02T15:04:05Z\tINFO\tp\thello\tk=1"},
+		{"level first", []Column{LevelColumn, TimeColumn, MessageColumn}, false, "INFO\t2006-01-02T15:04:05Z\thello"},
+		{"message and fields", []Column{MessageColumn, FieldsColumn}, false, "hello\tk=1"},
+		{"prefix last", []Column{TimeColumn, MessageColumn, PrefixColumn}, false, "2006-01-02T15:04:05Z\thello\tp"},
+		// the caller column keeps its place even when empty
+		{"caller off", []Column{CallerColumn, MessageColumn}, false, "\thello"},
+		{"caller on", []Column{CallerColumn, MessageColumn}, true, "columns_test.go:LINE\thello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			cols := tt.columns
+			l.SetColumns(cols)
+			if cols != nil {
+				// l keeps a copy
+				cols[0] = FieldsColumn
+			}
+			l.SetCaller(tt.caller)
+			// inherited through Prefix
+			l.Prefix("p").With("k", 1).Info("hello")
+			want := strings.Replace(tt.want, "LINE", strconv.Itoa(line()-1), 1) + "\n"
+			if got := buf.String(); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestColumnsEmptyPrefix(t *testing.T) {
+	l, buf := newLogger()
+	l.SetColumns([]Column{PrefixColumn, MessageColumn})
+	l.Info("hello")
+	if got := buf.String(); got != "\thello\n" {
+		t.Errorf("got %q, want an empty prefix column", got)
+	}
+}
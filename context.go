@@ -0,0 +1,84 @@
+package log
+
+import "context"
+
+// contextKey is the type of the keys this package stores in a
+// context, so that they can't collide with anyone else's.
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	fieldsKey
+)
+
+// NewContext returns a copy of ctx carrying l, for FromContext to
+// find later.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext returns the logger stored in ctx by NewContext. If
+// there isn't one, it returns the logger used by the package-level
+// functions, so the result is always safe to use.
+func FromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(loggerKey).(Logger); ok {
+		return l
+	}
+	return current()
+}
+
+// ContextWith returns a copy of ctx carrying the field key=value, in
+// addition to any fields ctx already carries. The ...Context logging
+// methods add these fields to their line, which lets middleware
+// attach something like a request ID to every line logged while
+// handling that request.
+func ContextWith(ctx context.Context, key string, value interface{}) context.Context {
+	fields, _ := ctx.Value(fieldsKey).([]field)
+	fields = append(fields[:len(fields):len(fields)], field{key: key, value: value})
+	return context.WithValue(ctx, fieldsKey, fields)
+}
+
+// withContext returns l with the fields from ctx added, or l itself
+// if ctx carries none.
+func (l *DefaultLogger) withContext(ctx context.Context) *DefaultLogger {
+	fields, _ := ctx.Value(fieldsKey).([]field)
+	if len(fields) == 0 {
+		return l
+	}
+	nl := *l
+	nl.fields = append(l.fields[:len(l.fields):len(l.fields)], fields...)
+	return &nl
+}
+
+// DebugContext is like Debug, but adds the fields carried by ctx.
+func (l *DefaultLogger) DebugContext(ctx context.Context, format string, v ...interface{}) {
+	if !l.enabled(DebugLevel) {
+		return
+	}
+	l.withContext(ctx).Debug(format, v...)
+}
+
+// InfoContext is like Info, but adds the fields carried by ctx.
+func (l *DefaultLogger) InfoContext(ctx context.Context, format string, v ...interface{}) {
+	if !l.enabled(InfoLevel) {
+		return
+	}
+	l.withContext(ctx).Info(format, v...)
+}
+
+// WarningContext is like Warning, but adds the fields carried by
+// ctx.
+func (l *DefaultLogger) WarningContext(ctx context.Context, format string, v ...interface{}) {
+	if !l.enabled(WarningLevel) {
+		return
+	}
+	l.withContext(ctx).Warning(format, v...)
+}
+
+// ErrorContext is like Error, but adds the fields carried by ctx.
+func (l *DefaultLogger) ErrorContext(ctx context.Context, format string, v ...interface{}) {
+	if !l.enabled(ErrorLevel) {
+		return
+	}
+	l.withContext(ctx).Error(format, v...)
+}
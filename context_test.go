@@ -0,0 +1,56 @@
+package log
+
+import "context"
+import "testing"
+
+func TestFromContext(t *testing.T) {
+	std := NewTest()
+	SetDefault(std)
+	defer SetDefault(Default())
+
+	l := NewTest()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want Logger
+	}{
+		{"present", NewContext(context.Background(), l), l},
+		{"absent", context.Background(), std},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromContext(tt.ctx); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContextFields(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"none", context.Background(), "2006-01-02T15:04:05Z\tINFO\t\thello\n"},
+		{"one", ContextWith(context.Background(), "trace_id", "abc"),
+			"2006-01-02T15:04:05Z\tINFO\t\thello\ttrace_id=abc\n"},
+		{"two", ContextWith(ContextWith(context.Background(), "trace_id", "abc"), "user", 7),
+			"2006-01-02T15:04:05Z\tINFO\t\thello\ttrace_id=abc\tuser=7\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			l.InfoContext(tt.ctx, "hello")
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			// the fields are only for the one line
+			buf.Reset()
+			l.Info("hello")
+			if want := "2006-01-02T15:04:05Z\tINFO\t\thello\n"; buf.String() != want {
+				t.Errorf("later line got %q, want %q", buf.String(), want)
+			}
+		})
+	}
+}
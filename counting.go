@@ -0,0 +1,103 @@
+package log
+
+import "fmt"
+import "sync"
+
+// A CountingNullLogger discards log data like a NullLogger, but
+// counts how many calls were made at each level, which is handy for
+// testing code that logs.
+type CountingNullLogger struct {
+	mu     sync.Mutex
+	counts map[Level]int
+}
+
+// CountingNull returns a CountingNullLogger with all counts at zero.
+func CountingNull() *CountingNullLogger {
+	return &CountingNullLogger{counts: make(map[Level]int)}
+}
+
+// Counts returns a copy of the number of calls made at each level,
+// including through loggers derived via Prefix and With.
+func (c *CountingNullLogger) Counts() map[Level]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[Level]int, len(c.counts))
+	for lv, n := range c.counts {
+		counts[lv] = n
+	}
+	return counts
+}
+
+func (c *CountingNullLogger) count(level Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[level]++
+}
+
+// Log counts the call and discards the logged data, except that it
+// panics at PanicLevel and exits at FatalLevel like Panic and Fatal.
+func (c *CountingNullLogger) Log(level Level, format string, v ...interface{}) {
+	c.count(level)
+	switch {
+	case level >= FatalLevel:
+		osExit(1)
+	case level == PanicLevel:
+		panic(fmt.Sprintf(format, v...))
+	}
+}
+
+// Debug counts and discards the logged data.
+func (c *CountingNullLogger) Debug(format string, v ...interface{}) {
+	c.Log(DebugLevel, format, v...)
+}
+
+// Info counts and discards the logged data.
+func (c *CountingNullLogger) Info(format string, v ...interface{}) {
+	c.Log(InfoLevel, format, v...)
+}
+
+// Warning counts and discards the logged data.
+func (c *CountingNullLogger) Warning(format string, v ...interface{}) {
+	c.Log(WarningLevel, format, v...)
+}
+
+// Error counts and discards the logged data.
+func (c *CountingNullLogger) Error(format string, v ...interface{}) {
+	c.Log(ErrorLevel, format, v...)
+}
+
+// Panic counts the call, formats the data into a string, then
+// panics.
+func (c *CountingNullLogger) Panic(format string, v ...interface{}) {
+	c.Log(PanicLevel, format, v...)
+}
+
+// Fatal counts the call, discards the logged data, then exits the
+// process with status 1.
+func (c *CountingNullLogger) Fatal(format string, v ...interface{}) {
+	c.Log(FatalLevel, format, v...)
+}
+
+// Enabled reports true, since c counts every call.
+func (c *CountingNullLogger) Enabled(level Level) bool {
+	return true
+}
+
+// Must calls c.Panic() if err is not nil.
+func (c *CountingNullLogger) Must(message string, err error) {
+	if err != nil {
+		c.Panic("Failed to %s: %v", message, err)
+	}
+}
+
+// Prefix returns c itself, so that the counts are shared, and
+// discards the provided prefix.
+func (c *CountingNullLogger) Prefix(prefix string) Logger {
+	return c
+}
+
+// With returns c itself, so that the counts are shared, and discards
+// the provided field.
+func (c *CountingNullLogger) With(key string, value interface{}) Logger {
+	return c
+}
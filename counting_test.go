@@ -0,0 +1,33 @@
+package log
+
+import "reflect"
+import "testing"
+
+func TestCountingNull(t *testing.T) {
+	stubExit(t)
+	c := CountingNull()
+	// derived loggers count into the same counters
+	p := c.Prefix("p").With("k", "v")
+
+	c.Info("one")
+	p.Info("two")
+	p.Warning("three")
+	c.Log(DebugLevel, "four")
+	c.Error("five")
+	if v := catchPanic(func() { p.Panic("six") }); v != "six" {
+		t.Errorf("panicked with %v, want six", v)
+	}
+	c.Fatal("seven")
+
+	want := map[Level]int{
+		DebugLevel:   1,
+		InfoLevel:    2,
+		WarningLevel: 1,
+		ErrorLevel:   1,
+		PanicLevel:   1,
+		FatalLevel:   1,
+	}
+	if got := c.Counts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package log
+
+import "io"
+import "fmt"
+import "bytes"
+import "strings"
+import "strconv"
+import "encoding/csv"
+
+// NewCSV returns a logger that writes one CSV record per line to w,
+// with the columns time, level, prefix and msg, and a last column
+// holding any fields added with With as a JSON object, or empty if
+// there are none. Caller lookup adds a caller column before msg.
+// Values are quoted and escaped as encoding/csv does, so the output
+// can be read back with a csv.Reader.
+func NewCSV(w io.Writer) (l *DefaultLogger) {
+	l = NewWriter(w)
+	l.format = formatCSV
+	return
+}
+
+// csvLine renders e as a CSV record, without the record terminator.
+func csvLine(e entry) []byte {
+	var record []string
+	if e.severity {
+		record = append(record, strconv.Itoa(e.level.Severity()))
+	}
+	if e.time != "" {
+		record = append(record, e.time)
+	}
+	record = append(record, e.level.String(), e.prefix)
+	if e.caller != "" {
+		record = append(record, e.caller)
+	}
+	record = append(record, e.msg, csvFields(e.fields))
+	if e.stack != "" {
+		record = append(record, e.stack)
+	}
+
+	var b bytes.Buffer
+	cw := csv.NewWriter(&b)
+	cw.Write(record)
+	// each record is flushed on its own, so none is left behind
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		panic(fmt.Sprintf("Failed to encode log!\nError: %v\nLog: %s\n", err, e.msg))
+	}
+	return []byte(strings.TrimSuffix(b.String(), "\n"))
+}
+
+// csvFields renders the fields as a JSON object, in the order they
+// were added, or as nothing if there are none.
+func csvFields(fields []field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	var b bytes.Buffer
+	b.WriteByte('{')
+	jsonFields(&b, fields, func(string) bool { return false })
+	b.WriteByte('}')
+	return b.String()
+}
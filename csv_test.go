@@ -0,0 +1,50 @@
+package log
+
+import "testing"
+import "strings"
+import "encoding/csv"
+import "encoding/json"
+
+func TestCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+	}{
+		{"plain", "hello"},
+		{"comma", "one, two"},
+		{"quotes", `say "hi"`},
+		{"newline", "line one\nline two"},
+		{"leading space", " padded "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &CaptureBuffer{}
+			l := NewCSV(buf)
+			l.SetClock(FixedClock(testTime))
+			l.Prefix("db").With("rows", 3).With("q", `a,"b"`).Info("%s", tt.msg)
+			l.Warning("second")
+
+			records, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+			if err != nil {
+				t.Fatalf("invalid CSV %q: %v", buf.String(), err)
+			}
+			if len(records) != 2 {
+				t.Fatalf("got %d records, want 2: %q", len(records), records)
+			}
+			r := records[0]
+			if len(r) != 5 || r[0] != "2006-01-02T15:04:05Z" || r[1] != "INFO" || r[2] != "db" || r[3] != tt.msg {
+				t.Errorf("got record %q", r)
+			}
+			var fields struct {
+				Rows int    `json:"rows"`
+				Q    string `json:"q"`
+			}
+			if err := json.Unmarshal([]byte(r[4]), &fields); err != nil || fields.Rows != 3 || fields.Q != `a,"b"` {
+				t.Errorf("got fields %q: %v", r[4], err)
+			}
+			if r := records[1]; r[1] != "WARN" || r[4] != "" {
+				t.Errorf("got record %q, want empty fields", r)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package log
+
+import "fmt"
+import "sync"
+import "time"
+
+// A DedupLogger collapses runs of identical lines before forwarding
+// them to another Logger, in the manner of syslog's "last message
+// repeated N times".
+type DedupLogger struct {
+	l  Logger
+	st *dedupState
+}
+
+// dedupState remembers the last line seen by a tree of DedupLoggers.
+type dedupState struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	timer   *time.Timer
+
+	from    *DedupLogger
+	level   Level
+	msg     string
+	repeats int
+
+	stats *stats
+}
+
+// Dedup returns a logger that forwards lines to l, except for lines
+// identical to the one before: those are counted, and the count is
+// written as "previous message repeated N times" when a different
+// line arrives, or once timeout has passed without one. A zero
+// timeout waits for a different line, or for Flush. Lines are
+// compared after formatting, along with their level and the logger
+// they were written to.
+func Dedup(l Logger, timeout time.Duration) *DedupLogger {
+	return &DedupLogger{l: l, st: &dedupState{timeout: timeout, stats: &stats{}}}
+}
+
+// summary returns a function writing the repeat count, if there is
+// one, and resets the count. The caller must hold st.mu.
+func (st *dedupState) summary() func() {
+	if st.repeats == 0 {
+		return func() {}
+	}
+	l, level, n := st.from.l, st.level, st.repeats
+	st.repeats = 0
+	if st.timer != nil {
+		st.timer.Stop()
+	}
+	return func() { l.Log(level, "previous message repeated %d times", n) }
+}
+
+// expire writes the repeat count once the timeout passes.
+func (st *dedupState) expire() {
+	st.mu.Lock()
+	flush := st.summary()
+	st.mu.Unlock()
+	flush()
+}
+
+// Log forwards to the wrapped logger unless it repeats the last
+// line. PanicLevel and above are always forwarded, after writing any
+// pending repeat count.
+func (d *DedupLogger) Log(level Level, format string, v ...interface{}) {
+	if level >= PanicLevel {
+		d.st.stats.count(level, true)
+		d.Flush()
+		d.l.Log(level, format, v...)
+		return
+	}
+
+	msg := fmt.Sprintf(format, v...)
+	st := d.st
+
+	st.mu.Lock()
+	if st.from == d && st.level == level && st.msg == msg {
+		st.repeats++
+		st.stats.count(level, false)
+		if st.timeout > 0 {
+			if st.timer == nil {
+				st.timer = time.AfterFunc(st.timeout, st.expire)
+			} else {
+				st.timer.Reset(st.timeout)
+			}
+		}
+		st.mu.Unlock()
+		return
+	}
+	flush := st.summary()
+	st.from, st.level, st.msg = d, level, msg
+	st.mu.Unlock()
+
+	st.stats.count(level, true)
+	flush()
+	d.l.Log(level, "%s", msg)
+}
+
+// Stats returns the number of lines forwarded and collapsed so far
+// by d and the loggers derived from it.
+func (d *DedupLogger) Stats() Stats {
+	return d.st.stats.snapshot()
+}
+
+// Flush writes the repeat count for the last line, if it has been
+// repeated.
+func (d *DedupLogger) Flush() {
+	d.st.expire()
+}
+
+// Debug forwards to the wrapped logger unless it repeats the last
+// line.
+func (d *DedupLogger) Debug(format string, v ...interface{}) {
+	d.Log(DebugLevel, format, v...)
+}
+
+// Info forwards to the wrapped logger unless it repeats the last
+// line.
+func (d *DedupLogger) Info(format string, v ...interface{}) {
+	d.Log(InfoLevel, format, v...)
+}
+
+// Warning forwards to the wrapped logger unless it repeats the last
+// line.
+func (d *DedupLogger) Warning(format string, v ...interface{}) {
+	d.Log(WarningLevel, format, v...)
+}
+
+// Error forwards to the wrapped logger unless it repeats the last
+// line.
+func (d *DedupLogger) Error(format string, v ...interface{}) {
+	d.Log(ErrorLevel, format, v...)
+}
+
+// Panic writes any pending repeat count, and then always forwards to
+// the wrapped logger.
+func (d *DedupLogger) Panic(format string, v ...interface{}) {
+	d.Log(PanicLevel, format, v...)
+}
+
+// Fatal writes any pending repeat count, and then always forwards to
+// the wrapped logger.
+func (d *DedupLogger) Fatal(format string, v ...interface{}) {
+	d.Log(FatalLevel, format, v...)
+}
+
+// Enabled reports whether the wrapped logger is enabled for level.
+func (d *DedupLogger) Enabled(level Level) bool {
+	return d.l.Enabled(level)
+}
+
+// Must forwards to the wrapped logger.
+func (d *DedupLogger) Must(message string, err error) {
+	d.l.Must(message, err)
+}
+
+// Prefix returns a deduplicating logger around the wrapped logger's
+// Prefix, sharing d's idea of the last line.
+func (d *DedupLogger) Prefix(prefix string) Logger {
+	return &DedupLogger{l: d.l.Prefix(prefix), st: d.st}
+}
+
+// With returns a deduplicating logger around the wrapped logger's
+// With, sharing d's idea of the last line.
+func (d *DedupLogger) With(key string, value interface{}) Logger {
+	return &DedupLogger{l: d.l.With(key, value), st: d.st}
+}
@@ -0,0 +1,112 @@
+package log
+
+import "time"
+import "testing"
+import "strings"
+
+// messages returns the level and message of each entry recorded by
+// tl.
+func messages(tl *TestLogger) []string {
+	var got []string
+	for _, e := range tl.Messages() {
+		got = append(got, e.Level.String()+" "+e.Message())
+	}
+	return got
+}
+
+func TestDedup(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(d *DedupLogger)
+		want []string
+	}{
+		{
+			"repeated then different",
+			func(d *DedupLogger) {
+				for i := 0; i < 4; i++ {
+					d.Info("disk %s", "full")
+				}
+				d.Info("disk ok")
+			},
+			[]string{"INFO disk full", "INFO previous message repeated 3 times", "INFO disk ok"},
+		},
+		{
+			"same format, different args",
+			func(d *DedupLogger) {
+				d.Info("n=%d", 1)
+				d.Info("n=%d", 2)
+			},
+			[]string{"INFO n=1", "INFO n=2"},
+		},
+		{
+			"different format, same message",
+			func(d *DedupLogger) {
+				d.Info("n=%d", 1)
+				d.Info("n=1")
+			},
+			[]string{"INFO n=1"},
+		},
+		{
+			"different level",
+			func(d *DedupLogger) {
+				d.Info("hello")
+				d.Warning("hello")
+			},
+			[]string{"INFO hello", "WARN hello"},
+		},
+		{
+			"different prefix",
+			func(d *DedupLogger) {
+				d.Info("hello")
+				d.Prefix("p").Info("hello")
+			},
+			[]string{"INFO hello", "INFO hello"},
+		},
+		{
+			"panic flushes",
+			func(d *DedupLogger) {
+				d.Error("hello")
+				d.Error("hello")
+				d.Panic("boom")
+			},
+			[]string{"ERROR hello", "ERROR previous message repeated 1 times", "PANIC boom"},
+		},
+		{
+			"flush",
+			func(d *DedupLogger) {
+				d.Info("hello")
+				d.Info("hello")
+				d.Flush()
+				d.Flush()
+			},
+			[]string{"INFO hello", "INFO previous message repeated 1 times"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl := NewTest()
+			tl.SetPanic(false)
+			tt.log(Dedup(tl, 0))
+			if got := messages(tl); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupTimeout(t *testing.T) {
+	tl := NewTest()
+	d := Dedup(tl, 10*time.Millisecond)
+	d.Info("hello")
+	d.Info("hello")
+	for deadline := time.Now().Add(5 * time.Second); len(tl.Messages()) < 2; {
+		if time.Now().After(deadline) {
+			t.Fatal("repeat count not written after the timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	want := "INFO previous message repeated 1 times"
+	if got := messages(tl); got[1] != want {
+		t.Errorf("got %q, want %q", got[1], want)
+	}
+}
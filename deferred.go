@@ -0,0 +1,183 @@
+package log
+
+import "fmt"
+import "sync"
+
+// A DeferredLogger holds back the lines below a reveal level, such
+// as the Info and Debug lines of a batch job, until a line at or
+// above it shows that something went wrong. It then writes the held
+// lines, oldest first, to give the failure its context, and from
+// then on forwards everything as it is logged. If the run succeeds,
+// Discard drops the held lines instead.
+type DeferredLogger struct {
+	l Logger
+	d *deferred
+}
+
+// deferred is the state shared by a tree of DeferredLoggers, holding
+// up to len(lines) lines starting at start.
+type deferred struct {
+	mu       sync.Mutex
+	reveal   Level
+	revealed bool
+	lines    []deferredLine
+	start    int
+	n        int
+}
+
+// deferredLine is a held line, with the logger it is to be written
+// to, so that it keeps its prefix and fields.
+type deferredLine struct {
+	l     Logger
+	level Level
+	msg   string
+}
+
+// Deferred returns a logger that holds the last capacity lines below
+// reveal, of those l is enabled for, and writes them to l once a
+// line at reveal or above is logged, or at PanicLevel or above
+// whatever reveal is. Older lines are dropped as the store fills.
+// Loggers derived via Prefix and With share the store, and reveal
+// together.
+func Deferred(l Logger, capacity int, reveal Level) *DeferredLogger {
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &DeferredLogger{
+		l: l,
+		d: &deferred{reveal: reveal, lines: make([]deferredLine, capacity)},
+	}
+}
+
+// hold stores a line, evicting the oldest if the store is full, and
+// reports whether it did, which it doesn't once revealed.
+func (d *deferred) hold(line deferredLine) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.revealed {
+		return false
+	}
+	if len(d.lines) == 0 {
+		return true
+	}
+	d.lines[(d.start+d.n)%len(d.lines)] = line
+	if d.n < len(d.lines) {
+		d.n++
+	} else {
+		d.start = (d.start + 1) % len(d.lines)
+	}
+	return true
+}
+
+// flush writes the held lines, if not yet revealed, switches to
+// forwarding, and then writes line. The lock is held throughout so
+// that lines logged meanwhile come after the held ones and line.
+func (d *deferred) flush(line deferredLine) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.revealed {
+		d.revealed = true
+		for i := 0; i < d.n; i++ {
+			held := d.lines[(d.start+i)%len(d.lines)]
+			held.l.Log(held.level, "%s", held.msg)
+		}
+		d.lines, d.start, d.n = nil, 0, 0
+	}
+	line.l.Log(line.level, "%s", line.msg)
+}
+
+// Discard drops the held lines, for when the run succeeded and they
+// aren't needed. Lines logged afterwards are held as before, unless
+// the lines have already been revealed.
+func (d *DeferredLogger) Discard() {
+	d.d.mu.Lock()
+	defer d.d.mu.Unlock()
+	for i := range d.d.lines {
+		d.d.lines[i] = deferredLine{}
+	}
+	d.d.start, d.d.n = 0, 0
+}
+
+// Revealed reports whether the held lines have been written, and d
+// is forwarding everything.
+func (d *DeferredLogger) Revealed() bool {
+	d.d.mu.Lock()
+	defer d.d.mu.Unlock()
+	return d.d.revealed
+}
+
+// Log holds the line if it is below the reveal level and nothing has
+// been revealed yet. Otherwise it first writes any held lines, and
+// then forwards the line, which panics at PanicLevel and exits at
+// FatalLevel, even if the wrapped logger is disabled there.
+func (d *DeferredLogger) Log(level Level, format string, v ...interface{}) {
+	if level < PanicLevel && !d.l.Enabled(level) {
+		return
+	}
+	line := deferredLine{l: d.l, level: level, msg: fmt.Sprintf(format, v...)}
+	if level >= d.d.reveal || level >= PanicLevel {
+		d.d.flush(line)
+		return
+	}
+	if !d.d.hold(line) {
+		d.l.Log(level, "%s", line.msg)
+	}
+}
+
+// Debug holds or forwards the line.
+func (d *DeferredLogger) Debug(format string, v ...interface{}) {
+	d.Log(DebugLevel, format, v...)
+}
+
+// Info holds or forwards the line.
+func (d *DeferredLogger) Info(format string, v ...interface{}) {
+	d.Log(InfoLevel, format, v...)
+}
+
+// Warning holds or forwards the line.
+func (d *DeferredLogger) Warning(format string, v ...interface{}) {
+	d.Log(WarningLevel, format, v...)
+}
+
+// Error holds or forwards the line.
+func (d *DeferredLogger) Error(format string, v ...interface{}) {
+	d.Log(ErrorLevel, format, v...)
+}
+
+// Panic writes the held lines, and then forwards the line to the
+// wrapped logger, which panics.
+func (d *DeferredLogger) Panic(format string, v ...interface{}) {
+	d.Log(PanicLevel, format, v...)
+}
+
+// Fatal writes the held lines, and then forwards the line to the
+// wrapped logger, which exits.
+func (d *DeferredLogger) Fatal(format string, v ...interface{}) {
+	d.Log(FatalLevel, format, v...)
+}
+
+// Enabled reports whether the wrapped logger is enabled for level.
+func (d *DeferredLogger) Enabled(level Level) bool {
+	return d.l.Enabled(level)
+}
+
+// Must calls d.Panic() if err is not nil, and otherwise forwards to
+// the wrapped logger.
+func (d *DeferredLogger) Must(message string, err error) {
+	if err != nil {
+		d.Panic("Failed to %s: %v", message, err)
+	}
+	d.l.Must(message, nil)
+}
+
+// Prefix returns a deferred logger around the wrapped logger's
+// Prefix, sharing d's store.
+func (d *DeferredLogger) Prefix(prefix string) Logger {
+	return &DeferredLogger{l: d.l.Prefix(prefix), d: d.d}
+}
+
+// With returns a deferred logger around the wrapped logger's With,
+// sharing d's store.
+func (d *DeferredLogger) With(key string, value interface{}) Logger {
+	return &DeferredLogger{l: d.l.With(key, value), d: d.d}
+}
@@ -0,0 +1,176 @@
+package log
+
+import "io"
+import "testing"
+import "strings"
+import "time"
+
+func TestDeferred(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		log      func(d *DeferredLogger)
+		want     []string
+	}{
+		{"held", 4, func(d *DeferredLogger) {
+			d.Debug("one")
+			d.Info("two")
+		}, nil},
+		{"revealed", 4, func(d *DeferredLogger) {
+			d.Debug("one")
+			d.Prefix("p").Info("two")
+			d.Error("failed")
+			d.Info("three")
+		}, []string{"DEBUG one", "INFO two", "ERROR failed", "INFO three"}},
+		{"above reveal", 4, func(d *DeferredLogger) {
+			d.Info("one")
+			d.Warning("two")
+		}, []string{"INFO one", "WARN two"}},
+		{"oldest dropped", 2, func(d *DeferredLogger) {
+			d.Info("one")
+			d.Info("two")
+			d.Info("three")
+			d.Error("failed")
+		}, []string{"INFO two", "INFO three", "ERROR failed"}},
+		{"no capacity", 0, func(d *DeferredLogger) {
+			d.Info("one")
+			d.Error("failed")
+		}, []string{"ERROR failed"}},
+		{"discarded", 4, func(d *DeferredLogger) {
+			d.Info("one")
+			d.Discard()
+			d.Info("two")
+			d.Error("failed")
+		}, []string{"INFO two", "ERROR failed"}},
+		{"discard after reveal", 4, func(d *DeferredLogger) {
+			d.Error("failed")
+			d.Discard()
+			d.Info("two")
+		}, []string{"ERROR failed", "INFO two"}},
+		{"panic reveals", 4, func(d *DeferredLogger) {
+			d.Info("one")
+			catchPanic(func() { d.Panic("boom") })
+		}, []string{"INFO one", "PANIC boom"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl := NewTest()
+			d := Deferred(tl, tt.capacity, WarningLevel)
+			tt.log(d)
+			if got := messages(tl); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeferredKeepsPrefix(t *testing.T) {
+	l, buf := newLogger()
+	d := Deferred(l, 4, ErrorLevel)
+	d.Prefix("a").With("k", 1).Info("one")
+	d.Prefix("b").Error("failed")
+	want := []string{
+		"2006-01-02T15:04:05Z\tINFO\ta\tone\tk=1",
+		"2006-01-02T15:04:05Z\tERROR\tb\tfailed",
+	}
+	if got := buf.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDeferredPanicsWhenDisabled(t *testing.T) {
+	tests := []struct {
+		name string
+		l    *DefaultLogger
+	}{
+		{"threshold", func() *DefaultLogger {
+			l, _ := newLogger()
+			l.SetLevel(FatalLevel)
+			return l
+		}()},
+		{"discarding", NewWriter(io.Discard)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.l.Enabled(PanicLevel) {
+				t.Fatal("enabled at PanicLevel")
+			}
+			d := Deferred(tt.l, 4, PanicLevel)
+			if v := catchPanic(func() { d.Panic("boom %d", 1) }); v != "boom 1" {
+				t.Errorf("panicked with %v, want boom 1", v)
+			}
+			if !d.Revealed() {
+				t.Error("not revealed by Panic")
+			}
+		})
+	}
+}
+
+func TestDeferredFatal(t *testing.T) {
+	code := stubExit(t)
+	tl := NewTest()
+	d := Deferred(tl, 4, PanicLevel)
+	d.Info("one")
+	d.Fatal("bye")
+	if got := messages(tl); strings.Join(got, "\n") != "INFO one\nFATAL bye" || *code != 1 {
+		t.Errorf("got %q and exit %d", got, *code)
+	}
+}
+
+// hookLogger calls hook before each line it logs.
+type hookLogger struct {
+	Logger
+	hook func(level Level)
+}
+
+func (h *hookLogger) Log(level Level, format string, v ...interface{}) {
+	h.hook(level)
+	h.Logger.Log(level, format, v...)
+}
+
+func TestDeferredRevealOrder(t *testing.T) {
+	tl := NewTest()
+	done := make(chan struct{})
+	var d *DeferredLogger
+	h := &hookLogger{Logger: tl, hook: func(level Level) {
+		switch level {
+		case DebugLevel:
+			// log from elsewhere while the held lines are written
+			go func() {
+				d.Info("other")
+				close(done)
+			}()
+		case ErrorLevel:
+			// give it a chance to come before the revealing line
+			time.Sleep(10 * time.Millisecond)
+		}
+	}}
+	d = Deferred(h, 4, ErrorLevel)
+	d.Debug("one")
+	d.Error("failed")
+	<-done
+	want := []string{"DEBUG one", "ERROR failed", "INFO other"}
+	if got := messages(tl); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// mustRecorder records the messages Must is called with.
+type mustRecorder struct {
+	Logger
+	musts []string
+}
+
+func (m *mustRecorder) Must(message string, err error) {
+	m.musts = append(m.musts, message)
+	m.Logger.Must(message, err)
+}
+
+func TestDeferredMustForwards(t *testing.T) {
+	m := &mustRecorder{Logger: NewTest()}
+	d := Deferred(m, 4, ErrorLevel)
+	d.Must("do it", nil)
+	if strings.Join(m.musts, ",") != "do it" {
+		t.Errorf("wrapped logger's Must got %q, want [do it]", m.musts)
+	}
+}
@@ -0,0 +1,53 @@
+package log
+
+import "os"
+import "fmt"
+import "strings"
+
+// FromEnv returns a stderr logger configured by the environment, so
+// that the verbosity of a deployed binary can be changed without
+// rebuilding it. It reads:
+//
+//	LOG_LEVEL   the threshold, as understood by ParseLevel
+//	LOG_FORMAT  "text", "json" or "logfmt"
+//	LOG_COLOR   "always", "never" or "auto" (color if a terminal)
+//
+// Unset variables keep the defaults of Default. Invalid values do
+// too, and the logger writes a single warning listing them.
+func FromEnv() *DefaultLogger {
+	l := Default()
+	var bad []string
+
+	if s := os.Getenv("LOG_LEVEL"); s != "" {
+		if level, err := ParseLevel(s); err == nil {
+			l.SetLevel(level)
+		} else {
+			bad = append(bad, fmt.Sprintf("LOG_LEVEL=%q", s))
+		}
+	}
+
+	switch s := os.Getenv("LOG_FORMAT"); strings.ToLower(s) {
+	case "", "text":
+	case "json":
+		l.format = formatJSON
+	case "logfmt":
+		l.format = formatLogfmt
+	default:
+		bad = append(bad, fmt.Sprintf("LOG_FORMAT=%q", s))
+	}
+
+	switch s := os.Getenv("LOG_COLOR"); strings.ToLower(s) {
+	case "", "never":
+	case "always":
+		l.SetColor(true)
+	case "auto":
+		l.AutoColor()
+	default:
+		bad = append(bad, fmt.Sprintf("LOG_COLOR=%q", s))
+	}
+
+	if len(bad) > 0 {
+		l.Warning("Ignoring invalid environment: %s", strings.Join(bad, ", "))
+	}
+	return l
+}
@@ -0,0 +1,75 @@
+package log
+
+import "io"
+import "os"
+import "testing"
+import "strings"
+
+// captureStderr returns what f writes to os.Stderr.
+func captureStderr(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = old }()
+
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+	f()
+	w.Close()
+	return <-out
+}
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		name          string
+		level, format string
+		color         string
+		wantLevel     Level
+		wantFormat    format
+		wantColor     bool
+		warning       string
+	}{
+		{"unset", "", "", "", InfoLevel, formatText, false, ""},
+		{"level", "warning", "", "", WarningLevel, formatText, false, ""},
+		{"level names ignore case", "DEBUG", "", "", DebugLevel, formatText, false, ""},
+		{"json", "", "JSON", "", InfoLevel, formatJSON, false, ""},
+		{"logfmt", "", "logfmt", "", InfoLevel, formatLogfmt, false, ""},
+		{"color", "", "", "always", InfoLevel, formatText, true, ""},
+		// stderr is a pipe here
+		{"auto color", "", "", "auto", InfoLevel, formatText, false, ""},
+		{"bad level", "loud", "", "", InfoLevel, formatText, false, `LOG_LEVEL="loud"`},
+		{
+			"several bad", "loud", "xml", "rainbow", InfoLevel, formatText, false,
+			`LOG_LEVEL="loud", LOG_FORMAT="xml", LOG_COLOR="rainbow"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LOG_LEVEL", tt.level)
+			t.Setenv("LOG_FORMAT", tt.format)
+			t.Setenv("LOG_COLOR", tt.color)
+			var l *DefaultLogger
+			out := captureStderr(t, func() { l = FromEnv() })
+
+			if l.Level() != tt.wantLevel || l.format != tt.wantFormat || l.color != tt.wantColor {
+				t.Errorf("got level %v, format %v, color %v", l.Level(), l.format, l.color)
+			}
+			if tt.warning == "" {
+				if out != "" {
+					t.Errorf("warned %q", out)
+				}
+				return
+			}
+			if strings.Count(out, "\n") != 1 || !strings.Contains(out, "Ignoring invalid environment: "+tt.warning+"\n") {
+				t.Errorf("warned %q, want one warning about %s", out, tt.warning)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+//go:build windows && eventlog
+
+package log
+
+import "fmt"
+import "golang.org/x/sys/windows/svc/eventlog"
+
+// This file is only built with -tags eventlog, so that only programs
+// that log to the Event Log need golang.org/x/sys.
+
+var _ Logger = (*eventLogger)(nil)
+
+// eventID is the event ID of every event an eventLogger writes.
+const eventID = 1
+
+// eventLogger writes to the Windows Event Log. Like syslog, it has
+// no notion of nesting, so the prefix and fields are written into
+// the message.
+type eventLogger struct {
+	w      *eventlog.Log
+	prefix string
+	fields []field
+}
+
+// NewEventLog returns a Logger that writes to the Windows Event Log
+// as source, which must already be registered, say by the service's
+// installer with eventlog.InstallAsEventCreate. Debug and Info are
+// written as information events, Warning as warnings, and Error and
+// above as errors. It is only available when built with -tags eventlog.
+func NewEventLog(source string) (Logger, error) {
+	w, err := eventlog.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	return &eventLogger{w: w}, nil
+}
+
+// message renders the prefix, text and fields as one event message.
+func (e *eventLogger) message(t string) string {
+	if e.prefix != "" {
+		t = e.prefix + ": " + t
+	}
+	return t + textFields(e.fields)
+}
+
+func (e *eventLogger) out(write func(uint32, string) error, t string) {
+	if err := write(eventID, e.message(t)); err != nil {
+		panic(fmt.Sprintf("Failed to write log!\nError: %v\nLog: %s\n", err, t))
+	}
+}
+
+// Debug writes an information event.
+func (e *eventLogger) Debug(format string, v ...interface{}) {
+	e.out(e.w.Info, fmt.Sprintf(format, v...))
+}
+
+// Info writes an information event.
+func (e *eventLogger) Info(format string, v ...interface{}) {
+	e.out(e.w.Info, fmt.Sprintf(format, v...))
+}
+
+// Warning writes a warning event.
+func (e *eventLogger) Warning(format string, v ...interface{}) {
+	e.out(e.w.Warning, fmt.Sprintf(format, v...))
+}
+
+// Error writes an error event.
+func (e *eventLogger) Error(format string, v ...interface{}) {
+	e.out(e.w.Error, fmt.Sprintf(format, v...))
+}
+
+// Panic writes an error event, and then panics.
+func (e *eventLogger) Panic(format string, v ...interface{}) {
+	t := fmt.Sprintf(format, v...)
+	e.out(e.w.Error, t)
+	panic(t)
+}
+
+// Fatal writes an error event, and then exits the process with
+// status 1.
+func (e *eventLogger) Fatal(format string, v ...interface{}) {
+	e.fatal(fmt.Sprintf(format, v...))
+	osExit(1)
+}
+
+func (e *eventLogger) fatal(t string) {
+	e.out(e.w.Error, t)
+}
+
+// Log writes the event type matching level, panicking and exiting at
+// PanicLevel and FatalLevel like Panic and Fatal.
+func (e *eventLogger) Log(level Level, format string, v ...interface{}) {
+	switch {
+	case level >= FatalLevel:
+		e.Fatal(format, v...)
+	case level == PanicLevel:
+		e.Panic(format, v...)
+	case level >= ErrorLevel:
+		e.Error(format, v...)
+	case level >= WarningLevel:
+		e.Warning(format, v...)
+	default:
+		e.Info(format, v...)
+	}
+}
+
+// Enabled reports true, leaving any filtering to the Event Log.
+func (e *eventLogger) Enabled(level Level) bool {
+	return true
+}
+
+// Must calls e.Panic() if err is not nil.
+func (e *eventLogger) Must(message string, err error) {
+	if err != nil {
+		e.Panic("Failed to %s: %v", message, err)
+	}
+}
+
+// CurrentPrefix returns the prefix accumulated by Prefix calls so
+// far.
+func (e *eventLogger) CurrentPrefix() string {
+	return e.prefix
+}
+
+// Prefix returns a new event logger with this prefix appended,
+// sharing the handle with e.
+func (e *eventLogger) Prefix(prefix string) Logger {
+	ne := *e
+	if e.prefix == "" {
+		ne.prefix = prefix
+	} else {
+		ne.prefix = e.prefix + ":" + prefix
+	}
+	return &ne
+}
+
+// With returns a new event logger that adds key=value to each
+// message.
+func (e *eventLogger) With(key string, value interface{}) Logger {
+	ne := *e
+	ne.fields = append(e.fields[:len(e.fields):len(e.fields)], field{key: key, value: value})
+	return &ne
+}
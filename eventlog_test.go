@@ -0,0 +1,58 @@
+//go:build windows && eventlog
+
+package log
+
+import "testing"
+import "golang.org/x/sys/windows/svc/eventlog"
+
+func TestEventLogMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix []string
+		fields []field
+		want   string
+	}{
+		{"plain", nil, nil, "hello"},
+		{"prefix", []string{"svc"}, nil, "svc: hello"},
+		{"nested prefix", []string{"svc", "db"}, nil, "svc:db: hello"},
+		{"fields", []string{"svc"}, []field{{key: "n", value: 1}}, "svc: hello\tn=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l Logger = &eventLogger{}
+			for _, p := range tt.prefix {
+				l = l.Prefix(p)
+			}
+			for _, f := range tt.fields {
+				l = l.With(f.key, f.value)
+			}
+			if got := l.(*eventLogger).message("hello"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventLog(t *testing.T) {
+	const source = "ispace-charrington-log-test"
+	if err := eventlog.InstallAsEventCreate(source, eventlog.Info|eventlog.Warning|eventlog.Error); err != nil {
+		t.Skipf("can't register an event source, which needs an administrator: %v", err)
+	}
+	defer eventlog.Remove(source)
+	l, err := NewEventLog(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := l.Prefix("test").With("k", 1)
+	for _, level := range []Level{TraceLevel, DebugLevel, InfoLevel, WarningLevel, ErrorLevel} {
+		pl.Log(level, "hello at %s", level)
+	}
+	if v := catchPanic(func() { pl.Panic("boom %d", 1) }); v != "boom 1" {
+		t.Errorf("panicked with %v, want boom 1", v)
+	}
+	code := stubExit(t)
+	pl.Fatal("bye")
+	if *code != 1 {
+		t.Errorf("exited with %d, want 1", *code)
+	}
+}
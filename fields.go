@@ -0,0 +1,333 @@
+package log
+
+import "fmt"
+import "os"
+import "sort"
+import "time"
+import "strconv"
+import "crypto/rand"
+import "encoding/hex"
+import "strings"
+import "sync/atomic"
+
+// A field is a key-value pair attached to every line written by a
+// logger, added with With.
+type field struct {
+	key   string
+	value interface{}
+
+	// isErr marks the field added by WithError, which JSON output
+	// calls "error" rather than "err".
+	isErr bool
+}
+
+// jsonKey returns the key the field has in JSON output.
+func (f field) jsonKey() string {
+	if f.isErr {
+		return "error"
+	}
+	return f.key
+}
+
+// With returns a new DefaultLogger that adds key=value to each line
+// it writes, after any fields l already carries. Loggers derived
+// from it via Prefix or With keep the field. Within a Group, the key
+// is qualified by the group's name.
+func (l *DefaultLogger) With(key string, value interface{}) Logger {
+	nl := *l
+	// cap the slice so that siblings never share an append
+	nl.fields = append(l.fields[:len(l.fields):len(l.fields)], field{key: qualify(l.group, key), value: value})
+	return &nl
+}
+
+// Group returns a new DefaultLogger that qualifies the keys of the
+// fields later added to it with name and a dot, like a slog group,
+// so that l.Group("http").With("method", "GET") adds http.method.
+// Groups nest: a Group within it adds to the name, as in
+// http.request.method. Fields added before the Group keep their
+// keys.
+func (l *DefaultLogger) Group(name string) Logger {
+	nl := *l
+	nl.group = qualify(l.group, name)
+	return &nl
+}
+
+// qualify joins a group and a key with a dot.
+func qualify(group, key string) string {
+	if group == "" {
+		return key
+	}
+	if key == "" {
+		return group
+	}
+	return group + "." + key
+}
+
+// WithError returns a new DefaultLogger that adds err to each line
+// it writes, as an err=... column in text output or an "error" key
+// in JSON output. If err is nil it returns l itself, so that
+//
+//	l.WithError(err).Warning("operation failed")
+//
+// is safe whether or not anything went wrong.
+func (l *DefaultLogger) WithError(err error) Logger {
+	if err == nil {
+		return l
+	}
+	nl := *l
+	nl.fields = append(l.fields[:len(l.fields):len(l.fields)], field{key: "err", value: err, isErr: true})
+	return &nl
+}
+
+// LogWith is like Log, but adds fields to just this one line,
+// rendered like those added with With, without deriving a logger.
+// The fields go after any l already carries, sorted by key, since a
+// map has no order of its own.
+func (l *DefaultLogger) LogWith(level Level, fields map[string]interface{}, format string, v ...interface{}) {
+	if level >= PanicLevel {
+		// rare enough that deriving a logger doesn't matter
+		nl := *l
+		nl.fields = append(l.fields[:len(l.fields):len(l.fields)], l.mapFields(fields)...)
+		nl.Log(level, format, v...)
+		return
+	}
+	if !l.enabled(level) {
+		return
+	}
+	e := l.entry(level, l.sprintf(level, format, v))
+	e.fields = append(e.fields[:len(e.fields):len(e.fields)], l.mapFields(fields)...)
+	l.outEntry(e)
+}
+
+// mapFields returns fields as a slice sorted by key, with the keys
+// qualified by l's group.
+func (l *DefaultLogger) mapFields(fields map[string]interface{}) []field {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fs := make([]field, len(keys))
+	for i, k := range keys {
+		fs[i] = field{key: qualify(l.group, k), value: fields[k]}
+	}
+	return fs
+}
+
+// DebugWith is like Debug, but adds fields to just this one line.
+func (l *DefaultLogger) DebugWith(fields map[string]interface{}, format string, v ...interface{}) {
+	l.LogWith(DebugLevel, fields, format, v...)
+}
+
+// InfoWith is like Info, but adds fields to just this one line.
+func (l *DefaultLogger) InfoWith(fields map[string]interface{}, format string, v ...interface{}) {
+	l.LogWith(InfoLevel, fields, format, v...)
+}
+
+// WarningWith is like Warning, but adds fields to just this one
+// line.
+func (l *DefaultLogger) WarningWith(fields map[string]interface{}, format string, v ...interface{}) {
+	l.LogWith(WarningLevel, fields, format, v...)
+}
+
+// ErrorWith is like Error, but adds fields to just this one line.
+func (l *DefaultLogger) ErrorWith(fields map[string]interface{}, format string, v ...interface{}) {
+	l.LogWith(ErrorLevel, fields, format, v...)
+}
+
+// PanicWith is like Panic, but adds fields to the line.
+func (l *DefaultLogger) PanicWith(fields map[string]interface{}, format string, v ...interface{}) {
+	l.LogWith(PanicLevel, fields, format, v...)
+}
+
+// FatalWith is like Fatal, but adds fields to the line.
+func (l *DefaultLogger) FatalWith(fields map[string]interface{}, format string, v ...interface{}) {
+	l.LogWith(FatalLevel, fields, format, v...)
+}
+
+// timeFields returns fields with any time.Duration value rendered
+// as a string like "1.5s", and any time.Time value formatted like
+// the timestamp, so that they read the same in every format rather
+// than, say, as nanoseconds in JSON. It returns fields itself if
+// there are none.
+func (l *DefaultLogger) timeFields(fields []field) []field {
+	var out []field
+	for i, f := range fields {
+		var s string
+		switch v := f.value.(type) {
+		case time.Duration:
+			s = v.String()
+		case time.Time:
+			s = l.formatTime(v)
+		default:
+			continue
+		}
+		if out == nil {
+			// the fields may be shared with other loggers
+			out = append([]field(nil), fields...)
+		}
+		out[i].value = s
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// textFields renders the fields as tab-separated key=value columns,
+// each preceded by a tab.
+func textFields(fields []field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, f := range fields {
+		fmt.Fprintf(&b, "\t%s=%v", f.key, f.value)
+	}
+	return b.String()
+}
+
+// SetIncludePID adds the process ID to each line, as a pid field
+// before any others but seq. Loggers derived via Prefix inherit the
+// setting.
+func (l *DefaultLogger) SetIncludePID(enabled bool) {
+	l.pid = enabled
+}
+
+// SetIncludeHostname adds the hostname to each line, as a host field
+// before any others but seq and pid. The hostname is looked up
+// once, now, rather than for every line. Loggers derived via Prefix
+// inherit the setting.
+func (l *DefaultLogger) SetIncludeHostname(enabled bool) {
+	if !enabled {
+		l.hostname = ""
+		return
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	l.hostname = host
+}
+
+// processFields returns l's fields with the seq, pid, host and
+// correlation_id fields in front, as enabled.
+func (l *DefaultLogger) processFields() []field {
+	fields := make([]field, 0, len(l.fields)+4)
+	if l.seq != nil {
+		fields = append(fields, field{key: "seq", value: atomic.AddUint64(l.seq, 1)})
+	}
+	if l.pid {
+		fields = append(fields, field{key: "pid", value: os.Getpid()})
+	}
+	if l.hostname != "" {
+		fields = append(fields, field{key: "host", value: l.hostname})
+	}
+	if l.corrID != "" {
+		fields = append(fields, field{key: "correlation_id", value: l.corrID})
+	}
+	return append(fields, l.fields...)
+}
+
+// SetSequence makes l number the lines it writes at or above its
+// threshold, in a seq field before any others, so that a gap in the
+// numbers shows a line was lost on its way to storage. The counter
+// starts at 1 and is shared by the loggers derived from l afterwards
+// via Prefix and With, so the numbers run across all of them; lines
+// written concurrently take their numbers in no particular order.
+func (l *DefaultLogger) SetSequence(enabled bool) {
+	if !enabled {
+		l.seq = nil
+	} else if l.seq == nil {
+		l.seq = new(uint64)
+	}
+}
+
+// WithCorrelationID returns a new DefaultLogger that adds id to each
+// line it writes, as a correlation_id field before any others but
+// seq, pid and host, so that every line logged on behalf of, say, one
+// request can be traced. An empty id generates a random one. Unlike
+// a field added with With, the ID replaces any l already carries,
+// and CorrelationID returns it. Loggers derived from the new logger
+// keep the ID.
+func (l *DefaultLogger) WithCorrelationID(id string) Logger {
+	if id == "" {
+		id = newCorrelationID()
+	}
+	nl := *l
+	nl.corrID = id
+	return &nl
+}
+
+// CorrelationID returns the ID added by WithCorrelationID, or ""
+// if there isn't one.
+func (l *DefaultLogger) CorrelationID() string {
+	return l.corrID
+}
+
+// newCorrelationID returns 16 random hex digits.
+func newCorrelationID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// the system's randomness failing is rare enough that a
+		// time-based ID will do
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// SetRedactKeys makes l write "***" in place of the value of any
+// field whose key is one of keys, ignoring case, so that values such
+// as passwords and tokens stay out of the logs. It applies to fields
+// added with With and to per-call fields alike, in every format,
+// and to keys qualified by a Group or slog group, so that password
+// masks http.password too. Calling it again replaces the keys, and
+// no keys turns redaction off. Loggers derived via Prefix inherit
+// the keys.
+func (l *DefaultLogger) SetRedactKeys(keys ...string) {
+	if len(keys) == 0 {
+		l.redact = nil
+		return
+	}
+	redact := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		redact[strings.ToLower(k)] = true
+	}
+	l.redact = redact
+}
+
+// redacts reports whether key is one of the redacted keys, or is
+// qualified by a group and ends in one, as http.password does.
+func (l *DefaultLogger) redacts(key string) bool {
+	key = strings.ToLower(key)
+	if l.redact[key] {
+		return true
+	}
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		return l.redact[key[i+1:]]
+	}
+	return false
+}
+
+// redacted returns fields with the values of redacted keys masked,
+// or fields itself if none are.
+func (l *DefaultLogger) redacted(fields []field) []field {
+	if len(l.redact) == 0 {
+		return fields
+	}
+	var out []field
+	for i, f := range fields {
+		if !l.redacts(f.key) && !l.redacts(f.jsonKey()) {
+			continue
+		}
+		if out == nil {
+			// the fields may be shared with other loggers
+			out = append([]field(nil), fields...)
+		}
+		out[i].value = "***"
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
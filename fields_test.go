@@ -0,0 +1,446 @@
+package log
+
+import "errors"
+import "os"
+import "strconv"
+import "sync"
+import "time"
+import "testing"
+import "strings"
+import "encoding/json"
+
+func TestWithError(t *testing.T) {
+	errBroken := errors.New("broken pipe")
+	tests := []struct {
+		name string
+		new  func() (*DefaultLogger, *CaptureBuffer)
+		err  error
+		want string
+	}{
+		{"text", newLogger, errBroken, "2006-01-02T15:04:05Z\tWARN\t\tfailed\terr=broken pipe\n"},
+		{"text nil", newLogger, nil, "2006-01-02T15:04:05Z\tWARN\t\tfailed\n"},
+		{"JSON", newJSONLogger, errBroken, `{"time":"2006-01-02T15:04:05Z","level":"WARN","prefix":"","msg":"failed","error":"broken pipe"}` + "\n"},
+		{"JSON nil", newJSONLogger, nil, `{"time":"2006-01-02T15:04:05Z","level":"WARN","prefix":"","msg":"failed"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := tt.new()
+			el := l.WithError(tt.err)
+			if tt.err == nil && el != Logger(l) {
+				t.Errorf("WithError(nil) returned a new logger")
+			}
+			el.Warning("failed")
+			if buf.String() != tt.want {
+				t.Errorf("got %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+// newJSONLogger is newLogger for JSON output.
+func newJSONLogger() (*DefaultLogger, *CaptureBuffer) {
+	buf := &CaptureBuffer{}
+	l := NewJSON(buf)
+	l.SetClock(FixedClock(testTime))
+	return l, buf
+}
+
+func TestIncludePIDAndHostname(t *testing.T) {
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "unknown"
+	}
+	pid := strconv.Itoa(os.Getpid())
+	tests := []struct {
+		name      string
+		pid, host bool
+		want      string
+	}{
+		{"neither", false, false, ""},
+		{"pid", true, false, "\tpid=" + pid},
+		{"hostname", false, true, "\thost=" + host},
+		{"both", true, true, "\tpid=" + pid + "\thost=" + host},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			l.SetIncludePID(tt.pid)
+			l.SetIncludeHostname(tt.host)
+			// inherited through Prefix, and ahead of other fields
+			l.Prefix("p").With("k", 1).Info("hello")
+			want := "2006-01-02T15:04:05Z\tINFO\tp\thello" + tt.want + "\tk=1\n"
+			if buf.String() != want {
+				t.Errorf("got %q, want %q", buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestIncludePIDJSON(t *testing.T) {
+	l, buf := newJSONLogger()
+	l.SetIncludePID(true)
+	l.Info("hello")
+	var got struct {
+		PID int `json:"pid"`
+	}
+	if err := json.Unmarshal([]byte(buf.String()), &got); err != nil || got.PID != os.Getpid() {
+		t.Errorf("got %q, want pid %d", buf.String(), os.Getpid())
+	}
+}
+
+func TestFieldOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func() (*DefaultLogger, *CaptureBuffer)
+		want string
+	}{
+		{"text", newLogger, "\tz=1\ta=2\tm=3\tb=4\ty=5\n"},
+		{"JSON", newJSONLogger, `,"z":1,"a":2,"m":3,"b":4,"y":5}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// many times over, since map order would differ
+			for i := 0; i < 20; i++ {
+				l, buf := tt.new()
+				// insertion order, per-call fields last and sorted
+				l.With("z", 1).With("a", 2).With("m", 3).(*DefaultLogger).
+					InfoWith(map[string]interface{}{"y": 5, "b": 4}, "hello")
+				if got := buf.String(); !strings.HasSuffix(got, tt.want) {
+					t.Fatalf("got %q, want it to end %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFieldsNotShared(t *testing.T) {
+	l, buf := newLogger()
+	base := l.With("a", 1)
+	// siblings derived from the same logger must not see each
+	// other's fields
+	one := base.With("b", 2)
+	two := base.With("c", 3)
+	one.Info("one")
+	two.Info("two")
+	want := []string{
+		"2006-01-02T15:04:05Z\tINFO\t\tone\ta=1\tb=2",
+		"2006-01-02T15:04:05Z\tINFO\t\ttwo\ta=1\tc=3",
+	}
+	if got := buf.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLogWith(t *testing.T) {
+	tests := []struct {
+		name  string
+		log   func(l *DefaultLogger, fields map[string]interface{})
+		level string
+	}{
+		{"DebugWith", func(l *DefaultLogger, f map[string]interface{}) { l.DebugWith(f, "hello %d", 1) }, "DEBUG"},
+		{"InfoWith", func(l *DefaultLogger, f map[string]interface{}) { l.InfoWith(f, "hello %d", 1) }, "INFO"},
+		{"WarningWith", func(l *DefaultLogger, f map[string]interface{}) { l.WarningWith(f, "hello %d", 1) }, "WARN"},
+		{"ErrorWith", func(l *DefaultLogger, f map[string]interface{}) { l.ErrorWith(f, "hello %d", 1) }, "ERROR"},
+		{"PanicWith", func(l *DefaultLogger, f map[string]interface{}) {
+			catchPanic(func() { l.PanicWith(f, "hello %d", 1) })
+		}, "PANIC"},
+		{"FatalWith", func(l *DefaultLogger, f map[string]interface{}) { l.FatalWith(f, "hello %d", 1) }, "FATAL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubExit(t)
+			l, buf := newLogger()
+			l.SetLevel(DebugLevel)
+			pl := l.With("a", 1).(*DefaultLogger)
+			tt.log(pl, map[string]interface{}{"req": 7})
+			// only on the one line
+			pl.Info("next")
+			want := []string{
+				"2006-01-02T15:04:05Z\t" + tt.level + "\t\thello 1\ta=1\treq=7",
+				"2006-01-02T15:04:05Z\tINFO\t\tnext\ta=1",
+			}
+			if got := buf.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestLogWithJSON(t *testing.T) {
+	l, buf := newJSONLogger()
+	l.InfoWith(map[string]interface{}{"req": 7}, "hello")
+	want := `{"time":"2006-01-02T15:04:05Z","level":"INFO","prefix":"","msg":"hello","req":7}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestLogWithGroup(t *testing.T) {
+	l, buf := newLogger()
+	l.Group("http").(*DefaultLogger).InfoWith(map[string]interface{}{"method": "GET"}, "hello")
+	if got := buf.String(); !strings.HasSuffix(got, "\thello\thttp.method=GET\n") {
+		t.Errorf("got %q, want the key qualified by the group", got)
+	}
+}
+
+func TestCorrelationID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{"given", "req-42"},
+		{"generated", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			cl := l.WithCorrelationID(tt.id)
+			id := cl.(*DefaultLogger).CorrelationID()
+			if tt.id != "" && id != tt.id {
+				t.Fatalf("got ID %q, want %q", id, tt.id)
+			}
+			if tt.id == "" {
+				if _, err := strconv.ParseUint(id, 16, 64); err != nil || len(id) != 16 {
+					t.Fatalf("got ID %q, want 16 hex digits", id)
+				}
+			}
+			cl.Info("one")
+			cl.Prefix("a").Info("two")
+			cl.Prefix("a").Prefix("b").With("k", 1).Info("three")
+			want := []string{
+				"2006-01-02T15:04:05Z\tINFO\t\tone\tcorrelation_id=" + id,
+				"2006-01-02T15:04:05Z\tINFO\ta\ttwo\tcorrelation_id=" + id,
+				"2006-01-02T15:04:05Z\tINFO\ta:b\tthree\tcorrelation_id=" + id + "\tk=1",
+			}
+			if got := buf.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("got %q, want %q", got, want)
+			}
+			if l.CorrelationID() != "" {
+				t.Errorf("the original logger got ID %q", l.CorrelationID())
+			}
+		})
+	}
+}
+
+func TestCorrelationIDReplaced(t *testing.T) {
+	l, buf := newJSONLogger()
+	l.WithCorrelationID("outer").Prefix("p").(*DefaultLogger).WithCorrelationID("inner").Info("hello")
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(buf.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["correlation_id"] != "inner" || strings.Count(buf.String(), "correlation_id") != 1 {
+		t.Errorf("got %q, want only the inner ID", buf.String())
+	}
+}
+
+func TestCorrelationIDsDiffer(t *testing.T) {
+	l, _ := newLogger()
+	one := l.WithCorrelationID("").(*DefaultLogger).CorrelationID()
+	two := l.WithCorrelationID("").(*DefaultLogger).CorrelationID()
+	if one == two {
+		t.Errorf("got %q twice", one)
+	}
+}
+
+func TestRedactKeys(t *testing.T) {
+	newLogfmt := func() (*DefaultLogger, *CaptureBuffer) {
+		buf := &CaptureBuffer{}
+		l := NewLogfmt(buf)
+		l.SetClock(FixedClock(testTime))
+		return l, buf
+	}
+	tests := []struct {
+		name string
+		new  func() (*DefaultLogger, *CaptureBuffer)
+		want string
+	}{
+		{"text", newLogger, "\tPASSWORD=***\tuser=bob\tToken=***\tn=1\n"},
+		{"JSON", newJSONLogger, `,"PASSWORD":"***","user":"bob","Token":"***","n":1}` + "\n"},
+		{"logfmt", newLogfmt, " PASSWORD=*** user=bob Token=*** n=1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := tt.new()
+			l.SetRedactKeys("password", "TOKEN")
+			// persistent and per-call fields, through Prefix
+			l.Prefix("p").With("PASSWORD", "hunter2").With("user", "bob").(*DefaultLogger).
+				InfoWith(map[string]interface{}{"Token": "abc", "n": 1}, "login")
+			got := buf.String()
+			if !strings.HasSuffix(got, tt.want) {
+				t.Errorf("got %q, want it to end %q", got, tt.want)
+			}
+			if strings.Contains(got, "hunter2") || strings.Contains(got, "abc") {
+				t.Errorf("got %q, leaking a secret", got)
+			}
+		})
+	}
+}
+
+func TestRedactKeysOff(t *testing.T) {
+	l, buf := newLogger()
+	l.SetRedactKeys("password")
+	l.SetRedactKeys()
+	l.With("password", "hunter2").Info("login")
+	if want := "2006-01-02T15:04:05Z\tINFO\t\tlogin\tpassword=hunter2\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestTimeFields(t *testing.T) {
+	at := time.Date(2020, 5, 6, 9, 30, 0, 0, time.FixedZone("EST", -5*3600))
+	tests := []struct {
+		name   string
+		new    func() (*DefaultLogger, *CaptureBuffer)
+		layout string
+		want   string
+	}{
+		{"text", newLogger, "", "\ttook=1.5s\tat=2020-05-06T14:30:00Z\tzero=0s\n"},
+		{"text layout", newLogger, "15:04", "\ttook=1.5s\tat=14:30\tzero=0s\n"},
+		{"JSON", newJSONLogger, "", `,"took":"1.5s","at":"2020-05-06T14:30:00Z","zero":"0s"}` + "\n"},
+		{"JSON layout", newJSONLogger, "15:04", `,"took":"1.5s","at":"14:30","zero":"0s"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := tt.new()
+			l.SetTimeFormat(tt.layout)
+			l.With("took", 1500*time.Millisecond).With("at", at).(*DefaultLogger).
+				InfoWith(map[string]interface{}{"zero": time.Duration(0)}, "done")
+			if got := buf.String(); !strings.HasSuffix(got, tt.want) {
+				t.Errorf("got %q, want it to end %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactGroupedKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l *DefaultLogger)
+		want string
+	}{
+		{"group", func(l *DefaultLogger) {
+			l.Group("http").With("Password", "hunter2").Info("login")
+		}, "\thttp.Password=***\n"},
+		{"nested groups", func(l *DefaultLogger) {
+			l.Group("http").(*DefaultLogger).Group("req").With("token", "abc").With("method", "GET").Info("login")
+		}, "\thttp.req.token=***\thttp.req.method=GET\n"},
+		{"qualified key", func(l *DefaultLogger) {
+			l.Group("db").With("secret", "abc").With("user", "bob").Info("login")
+		}, "\tdb.secret=***\tdb.user=bob\n"},
+		{"not a segment", func(l *DefaultLogger) {
+			l.With("passwordless", true).Info("login")
+		}, "\tpasswordless=true\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			l.SetRedactKeys("password", "token", "db.secret")
+			tt.log(l)
+			if got := buf.String(); !strings.HasSuffix(got, tt.want) {
+				t.Errorf("got %q, want it to end %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l *DefaultLogger)
+		want string
+	}{
+		{"one", func(l *DefaultLogger) {
+			l.Group("http").With("method", "GET").Info("hello")
+		}, "\thttp.method=GET\n"},
+		{"nested", func(l *DefaultLogger) {
+			l.Group("http").(*DefaultLogger).Group("request").With("method", "GET").Info("hello")
+		}, "\thttp.request.method=GET\n"},
+		{"earlier fields keep their keys", func(l *DefaultLogger) {
+			l.With("id", 1).(*DefaultLogger).Group("http").With("method", "GET").Info("hello")
+		}, "\tid=1\thttp.method=GET\n"},
+		{"through Prefix", func(l *DefaultLogger) {
+			l.Group("http").Prefix("p").With("method", "GET").Info("hello")
+		}, "\thttp.method=GET\n"},
+		{"empty name", func(l *DefaultLogger) {
+			l.Group("").With("method", "GET").Info("hello")
+		}, "\tmethod=GET\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			tt.log(l)
+			if got := buf.String(); !strings.HasSuffix(got, tt.want) {
+				t.Errorf("got %q, want it to end %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSequence(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(l *DefaultLogger)
+	}{
+		{"plain", func(*DefaultLogger) {}},
+		{"crash writer", func(l *DefaultLogger) { l.SetCrashWriter(&CaptureBuffer{}) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			l.SetSequence(true)
+			tt.setup(l)
+			pl := l.Prefix("p")
+			wl := pl.With("k", 1)
+			l.Info("one")
+			pl.Info("two")
+			l.Debug("hidden")
+			catchPanic(func() { wl.Panic("three") })
+			wl.Warning("four")
+			l.Error("five")
+			want := []string{
+				"2006-01-02T15:04:05Z\tINFO\t\tone\tseq=1",
+				"2006-01-02T15:04:05Z\tINFO\tp\ttwo\tseq=2",
+				"2006-01-02T15:04:05Z\tPANIC\tp\tthree\tseq=3\tk=1",
+				"2006-01-02T15:04:05Z\tWARN\tp\tfour\tseq=4\tk=1",
+				"2006-01-02T15:04:05Z\tERROR\t\tfive\tseq=5",
+			}
+			if got := buf.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSequenceConcurrent(t *testing.T) {
+	l, buf := newJSONLogger()
+	l.SetSequence(true)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(l Logger) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				l.Info("hello")
+			}
+		}(l.Prefix(strconv.Itoa(i)))
+	}
+	wg.Wait()
+	// every number once, whatever the order
+	seen := make(map[uint64]bool)
+	for _, line := range buf.Lines() {
+		var got struct {
+			Seq uint64 `json:"seq"`
+		}
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatal(err)
+		}
+		if seen[got.Seq] || got.Seq < 1 || got.Seq > 400 {
+			t.Fatalf("got seq %d again or out of range", got.Seq)
+		}
+		seen[got.Seq] = true
+	}
+	if len(seen) != 400 {
+		t.Errorf("got %d numbers, want 400", len(seen))
+	}
+}
@@ -0,0 +1,111 @@
+package log
+
+import "os"
+import "sync"
+import "errors"
+
+// NewFile returns a logger that appends to the file at path,
+// creating it if need be. The logger owns the file, so closing the
+// logger closes it, and Reopen can reopen it after log rotation.
+func NewFile(path string) (*DefaultLogger, error) {
+	f := &reopenFile{path: path}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return NewWriter(f), nil
+}
+
+// A reopenFile is a file that remembers its path, so that it can be
+// reopened after something like logrotate renames it.
+type reopenFile struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+// open opens the file at the path. The caller must hold f.mu, or be
+// its only user.
+func (f *reopenFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	f.f = file
+	return nil
+}
+
+func (f *reopenFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.f == nil {
+		return 0, os.ErrClosed
+	}
+	return f.f.Write(p)
+}
+
+func (f *reopenFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.f == nil {
+		return os.ErrClosed
+	}
+	return f.f.Sync()
+}
+
+// hasData reports whether the file has anything in it.
+func (f *reopenFile) hasData() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.f == nil {
+		return false
+	}
+	fi, err := f.f.Stat()
+	return err == nil && fi.Size() > 0
+}
+
+func (f *reopenFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.f == nil {
+		return os.ErrClosed
+	}
+	err := f.f.Close()
+	f.f = nil
+	return err
+}
+
+// Reopen closes the file opened by NewFile and opens the path again,
+// for logrotate-style rotation: once the file has been renamed,
+// usually followed by a SIGHUP, Reopen starts a fresh file at the
+// original path.
+//
+//	hup := make(chan os.Signal, 1)
+//	signal.Notify(hup, syscall.SIGHUP)
+//	go func() {
+//		for range hup {
+//			l.Reopen()
+//		}
+//	}()
+//
+// Loggers derived via Prefix and With write to the new file too.
+// Reopen returns an error if l wasn't made by NewFile.
+func (l *DefaultLogger) Reopen() error {
+	f, ok := l.writer().(*reopenFile)
+	if !ok {
+		return errors.New("log: Reopen needs a logger made by NewFile")
+	}
+	mu := l.mutex()
+	mu.Lock()
+	defer mu.Unlock()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.f != nil {
+		f.f.Sync()
+		f.f.Close()
+		f.f = nil
+	}
+	if l.header != nil {
+		l.header.written = false
+	}
+	return f.open()
+}
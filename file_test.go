@@ -0,0 +1,108 @@
+package log
+
+import "os"
+import "testing"
+import "strings"
+import "path/filepath"
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	l, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.SetClock(FixedClock(testTime))
+	pl := l.Prefix("p")
+
+	l.Info("before")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	// still writing to the renamed file until reopened
+	pl.Info("renamed")
+	if err := l.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("after")
+	pl.Info("derived")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path + ".1", "2006-01-02T15:04:05Z\tINFO\t\tbefore\n2006-01-02T15:04:05Z\tINFO\tp\trenamed\n"},
+		{path, "2006-01-02T15:04:05Z\tINFO\t\tafter\n2006-01-02T15:04:05Z\tINFO\tp\tderived\n"},
+	}
+	for _, tt := range tests {
+		if got := readFile(t, tt.path); got != tt.want {
+			t.Errorf("%s: got %q, want %q", filepath.Base(tt.path), got, tt.want)
+		}
+	}
+}
+
+func TestReopenAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.SetClock(FixedClock(testTime))
+	l.Info("one")
+	l.Reopen()
+	l.Info("two")
+	want := "old\n2006-01-02T15:04:05Z\tINFO\t\tone\n2006-01-02T15:04:05Z\tINFO\t\ttwo\n"
+	if got := readFile(t, path); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReopenNotFile(t *testing.T) {
+	l, _ := newLogger()
+	if err := l.Reopen(); err == nil {
+		t.Error("got nil, want an error")
+	}
+}
+
+func TestNewFileError(t *testing.T) {
+	if _, err := NewFile(filepath.Join(t.TempDir(), "missing", "app.log")); err == nil {
+		t.Error("got nil, want an error")
+	}
+}
+
+func TestMust(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name  string
+		path  string
+		fails bool
+	}{
+		{"ok", filepath.Join(dir, "app.log"), false},
+		{"error", filepath.Join(dir, "missing", "app.log"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l Logger
+			v := catchPanic(func() { l = Must(NewFile(tt.path)) })
+			if tt.fails {
+				if err, ok := v.(error); !ok || !os.IsNotExist(err) {
+					t.Errorf("panicked with %v, want the open error", v)
+				}
+				return
+			}
+			if v != nil {
+				t.Fatalf("panicked with %v", v)
+			}
+			defer l.(*DefaultLogger).Close()
+			l.Info("hello")
+			if got := readFile(t, tt.path); !strings.HasSuffix(got, "\tINFO\t\thello\n") {
+				t.Errorf("got %q, want the line", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package log
+
+import "unicode"
+
+// A prefixer is a Logger that can report its accumulated prefix.
+type prefixer interface {
+	CurrentPrefix() string
+}
+
+// A FilterLogger applies a minimum level to another Logger according
+// to that logger's prefix, to silence noisy subsystems.
+type FilterLogger struct {
+	l     Logger
+	rules map[string]Level
+	min   Level
+	ok    bool
+	stats *stats
+}
+
+// FilterPrefix returns a logger that drops lines from l below the
+// level of the most specific rule matching l's prefix. A rule
+// matches a prefix equal to it, or one that starts with it followed
+// by a separator, so that with the default separator "http" matches
+// "http" and "http:access" but not "httpd". The rule "" matches
+// everything, and serves as a default. Where no rule matches, lines
+// pass through.
+//
+// The prefix is found through a CurrentPrefix method, as on
+// DefaultLogger; a Logger without one is treated as having an empty
+// prefix. Panic and Fatal are never dropped.
+func FilterPrefix(l Logger, rules map[string]Level) *FilterLogger {
+	r := make(map[string]Level, len(rules))
+	for k, v := range rules {
+		r[k] = v
+	}
+	return newFilter(l, r, &stats{})
+}
+
+// newFilter wraps l, finding the rule for its prefix once up front.
+func newFilter(l Logger, rules map[string]Level, st *stats) *FilterLogger {
+	f := &FilterLogger{l: l, rules: rules, stats: st}
+	f.min, f.ok = matchRule(rules, f.CurrentPrefix())
+	return f
+}
+
+// matchRule returns the level of the longest rule matching prefix.
+func matchRule(rules map[string]Level, prefix string) (Level, bool) {
+	var best string
+	var level Level
+	found := false
+	for key, lv := range rules {
+		if !prefixMatches(prefix, key) {
+			continue
+		}
+		if !found || len(key) > len(best) {
+			best, level, found = key, lv, true
+		}
+	}
+	return level, found
+}
+
+// prefixMatches reports whether key is prefix, or the start of it
+// up to a segment boundary.
+func prefixMatches(prefix, key string) bool {
+	if len(prefix) < len(key) || prefix[:len(key)] != key {
+		return false
+	}
+	if key == "" || len(prefix) == len(key) {
+		return true
+	}
+	r := rune(prefix[len(key)])
+	return !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-')
+}
+
+// CurrentPrefix returns the wrapped logger's prefix.
+func (f *FilterLogger) CurrentPrefix() string {
+	if p, ok := f.l.(prefixer); ok {
+		return p.CurrentPrefix()
+	}
+	return ""
+}
+
+// Stats returns the number of lines forwarded and dropped so far by
+// f and the loggers derived from it.
+func (f *FilterLogger) Stats() Stats {
+	return f.stats.snapshot()
+}
+
+// allowed reports whether the rules let lines at level through.
+func (f *FilterLogger) allowed(level Level) bool {
+	return !f.ok || level >= f.min || level >= PanicLevel
+}
+
+// Log forwards to the wrapped logger if the rules allow level.
+func (f *FilterLogger) Log(level Level, format string, v ...interface{}) {
+	ok := f.allowed(level)
+	f.stats.count(level, ok)
+	if ok {
+		f.l.Log(level, format, v...)
+	}
+}
+
+// Debug forwards to the wrapped logger if the rules allow it.
+func (f *FilterLogger) Debug(format string, v ...interface{}) {
+	f.Log(DebugLevel, format, v...)
+}
+
+// Info forwards to the wrapped logger if the rules allow it.
+func (f *FilterLogger) Info(format string, v ...interface{}) {
+	f.Log(InfoLevel, format, v...)
+}
+
+// Warning forwards to the wrapped logger if the rules allow it.
+func (f *FilterLogger) Warning(format string, v ...interface{}) {
+	f.Log(WarningLevel, format, v...)
+}
+
+// Error forwards to the wrapped logger if the rules allow it.
+func (f *FilterLogger) Error(format string, v ...interface{}) {
+	f.Log(ErrorLevel, format, v...)
+}
+
+// Panic always forwards to the wrapped logger.
+func (f *FilterLogger) Panic(format string, v ...interface{}) {
+	f.Log(PanicLevel, format, v...)
+}
+
+// Fatal always forwards to the wrapped logger.
+func (f *FilterLogger) Fatal(format string, v ...interface{}) {
+	f.Log(FatalLevel, format, v...)
+}
+
+// Enabled reports whether the rules allow level and the wrapped
+// logger is enabled for it.
+func (f *FilterLogger) Enabled(level Level) bool {
+	return f.allowed(level) && f.l.Enabled(level)
+}
+
+// Must forwards to the wrapped logger.
+func (f *FilterLogger) Must(message string, err error) {
+	f.l.Must(message, err)
+}
+
+// Prefix returns a filtering logger around the wrapped logger's
+// Prefix, with the rule for the new prefix.
+func (f *FilterLogger) Prefix(prefix string) Logger {
+	return newFilter(f.l.Prefix(prefix), f.rules, f.stats)
+}
+
+// With returns a filtering logger around the wrapped logger's With.
+func (f *FilterLogger) With(key string, value interface{}) Logger {
+	nf := *f
+	nf.l = f.l.With(key, value)
+	return &nf
+}
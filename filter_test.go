@@ -0,0 +1,70 @@
+package log
+
+import "testing"
+
+func TestFilterPrefix(t *testing.T) {
+	rules := map[string]Level{
+		"":            WarningLevel,
+		"http":        ErrorLevel,
+		"http:access": FatalLevel,
+		"db":          DebugLevel,
+	}
+	tests := []struct {
+		prefix []string
+		level  Level
+		want   bool
+	}{
+		{nil, InfoLevel, false},
+		{nil, WarningLevel, true},
+		{[]string{"db"}, DebugLevel, true},
+		{[]string{"db"}, TraceLevel, false},
+		{[]string{"db", "query"}, DebugLevel, true},
+		{[]string{"http"}, WarningLevel, false},
+		{[]string{"http"}, ErrorLevel, true},
+		{[]string{"http", "admin"}, ErrorLevel, true},
+		{[]string{"http", "access"}, ErrorLevel, false},
+		{[]string{"http", "access", "slow"}, ErrorLevel, false},
+		// Panic is never dropped
+		{[]string{"http", "access"}, PanicLevel, true},
+		// not a segment of "http"
+		{[]string{"httpd"}, WarningLevel, true},
+	}
+	for _, tt := range tests {
+		l, buf := newLogger()
+		l.SetLevel(TraceLevel)
+		var fl Logger = FilterPrefix(l, rules)
+		for _, p := range tt.prefix {
+			fl = fl.Prefix(p)
+		}
+		catchPanic(func() { fl.Log(tt.level, "hello") })
+		if written := buf.String() != ""; written != tt.want {
+			t.Errorf("%v at %v: written = %v, want %v", tt.prefix, tt.level, written, tt.want)
+		}
+		if fl.Enabled(tt.level) != tt.want {
+			t.Errorf("%v: Enabled(%v) = %v, want %v", tt.prefix, tt.level, !tt.want, tt.want)
+		}
+	}
+}
+
+func TestFilterPrefixCopiesRules(t *testing.T) {
+	l, buf := newLogger()
+	rules := map[string]Level{"": ErrorLevel}
+	f := FilterPrefix(l, rules)
+	rules[""] = TraceLevel
+	f.Info("hello")
+	if buf.String() != "" {
+		t.Errorf("changing the rules afterwards changed the filter")
+	}
+}
+
+func TestFilterPrefixCurrentPrefix(t *testing.T) {
+	l, _ := newLogger()
+	fl := FilterPrefix(l, nil).Prefix("a").Prefix("b")
+	if p := fl.(*FilterLogger).CurrentPrefix(); p != "a:b" {
+		t.Errorf("got prefix %q, want a:b", p)
+	}
+	// a Logger without CurrentPrefix is treated as unprefixed
+	if p := FilterPrefix(Multi(NewTest()).Prefix("a"), nil).CurrentPrefix(); p != "" {
+		t.Errorf("got prefix %q, want none", p)
+	}
+}
@@ -0,0 +1,82 @@
+package log
+
+import "time"
+
+// A Formatter renders a line for a DefaultLogger with a custom
+// format, such as GELF, set with SetFormatter. Format is given the
+// line's level, time, prefix, message and fields, and returns the
+// line without its terminator, which the logger adds. The time is
+// zero if SetTimestamp turned timestamps off. An error is handled
+// like one from the writer, and the line isn't written.
+type Formatter interface {
+	Format(level Level, t time.Time, prefix, msg string, fields []Field) ([]byte, error)
+}
+
+// A Field is a key and value added to a line with With or one of
+// the field options, as passed to a Formatter.
+type Field struct {
+	Key   string
+	Value interface{}
+
+	// isErr marks the field added by WithError, so that the JSON
+	// formatter can call it "error"
+	isErr bool
+}
+
+// The built-in formats, as Formatters, so that a custom Formatter
+// can fall back on or wrap one of them. Standalone, they write the
+// time as RFC 3339 in UTC, and leave it out if it is zero.
+var (
+	TextFormatter   Formatter = lineFormatter(func(e entry) []byte { return textLine(e, false, nil) })
+	JSONFormatter   Formatter = lineFormatter(jsonLine)
+	LogfmtFormatter Formatter = lineFormatter(logfmtLine)
+	CSVFormatter    Formatter = lineFormatter(csvLine)
+)
+
+// lineFormatter adapts one of the functions rendering an entry into
+// a Formatter.
+type lineFormatter func(e entry) []byte
+
+// Format renders the line as the wrapped function does.
+func (f lineFormatter) Format(level Level, t time.Time, prefix, msg string, fields []Field) ([]byte, error) {
+	e := entry{at: t, level: level, prefix: prefix, msg: msg, fields: importFields(fields)}
+	if !t.IsZero() {
+		e.time = t.UTC().Format(time.RFC3339)
+	}
+	return f(e), nil
+}
+
+// SetFormatter makes l render its lines with f in place of its own
+// format, so that a format the package doesn't have can be written
+// without changing it. The caller, severity, column and single-line
+// settings only apply to the built-in formats, and are left to f to
+// handle or not; f is given the fields after redaction. A nil f goes
+// back to l's own format. Loggers derived via Prefix inherit the
+// formatter, and so it must be safe for concurrent use.
+func (l *DefaultLogger) SetFormatter(f Formatter) {
+	l.formatter = f
+}
+
+// exportFields converts fields for a Formatter.
+func exportFields(fields []field) []Field {
+	if len(fields) == 0 {
+		return nil
+	}
+	out := make([]Field, len(fields))
+	for i, f := range fields {
+		out[i] = Field{Key: f.key, Value: f.value, isErr: f.isErr}
+	}
+	return out
+}
+
+// importFields converts fields back from a Formatter.
+func importFields(fields []Field) []field {
+	if len(fields) == 0 {
+		return nil
+	}
+	out := make([]field, len(fields))
+	for i, f := range fields {
+		out[i] = field{key: f.Key, value: f.Value, isErr: f.isErr}
+	}
+	return out
+}
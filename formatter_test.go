@@ -0,0 +1,96 @@
+package log
+
+import "fmt"
+import "sync"
+import "time"
+import "errors"
+import "testing"
+import "strings"
+
+// pipeFormatter is a trivial custom format, recording its calls.
+type pipeFormatter struct {
+	mu    sync.Mutex
+	calls int
+	err   error
+}
+
+func (f *pipeFormatter) Format(level Level, t time.Time, prefix, msg string, fields []Field) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	s := fmt.Sprintf("%s|%s|%s|%s", t.Format(time.Kitchen), level, prefix, msg)
+	for _, fld := range fields {
+		s += fmt.Sprintf("|%s:%v", fld.Key, fld.Value)
+	}
+	return []byte(s), nil
+}
+
+func TestSetFormatter(t *testing.T) {
+	l, buf := newLogger()
+	f := &pipeFormatter{}
+	l.SetFormatter(f)
+	pl := l.Prefix("p")
+	l.Info("one")
+	pl.With("k", 1).Warning("two")
+	l.Debug("hidden")
+	want := []string{
+		"3:04PM|INFO||one",
+		"3:04PM|WARN|p|two|k:1",
+	}
+	if got := buf.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if f.calls != 2 {
+		t.Errorf("called %d times, want once per line", f.calls)
+	}
+
+	// nil goes back to the logger's own format
+	l.SetFormatter(nil)
+	buf.Reset()
+	l.Info("three")
+	if want := "2006-01-02T15:04:05Z\tINFO\t\tthree\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestSetFormatterError(t *testing.T) {
+	errBad := errors.New("bad value")
+	l, buf := newLogger()
+	l.SetFormatter(&pipeFormatter{err: errBad})
+	var got []error
+	l.SetErrorHandler(func(err error) { got = append(got, err) })
+	l.Info("one")
+	if len(got) != 1 || !errors.Is(got[0], errBad) || buf.String() != "" {
+		t.Errorf("got errors %v and output %q", got, buf.String())
+	}
+}
+
+func TestBuiltinFormatters(t *testing.T) {
+	tests := []struct {
+		name string
+		f    Formatter
+		want string
+	}{
+		{"text", TextFormatter, "2006-01-02T15:04:05Z\tINFO\tp\thello\tk=1"},
+		{"JSON", JSONFormatter, `{"time":"2006-01-02T15:04:05Z","level":"INFO","prefix":"p","msg":"hello","k":1}`},
+		{"logfmt", LogfmtFormatter, "time=2006-01-02T15:04:05Z level=INFO prefix=p msg=hello k=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.f.Format(InfoLevel, testTime, "p", "hello", []Field{{Key: "k", Value: 1}})
+			if err != nil || string(got) != tt.want {
+				t.Errorf("got %q, %v, want %q", got, err, tt.want)
+			}
+			// wrapped by a logger, as a custom formatter might be
+			l, buf := newLogger()
+			l.SetFormatter(tt.f)
+			l.Prefix("p").With("k", 1).Info("hello")
+			if buf.String() != tt.want+"\n" {
+				t.Errorf("logged %q, want %q", buf.String(), tt.want+"\n")
+			}
+		})
+	}
+}
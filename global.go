@@ -0,0 +1,57 @@
+package log
+
+import "sync"
+
+// The package-level logging functions write to std, which starts out
+// as a stderr DefaultLogger.
+var (
+	stdMu sync.RWMutex
+	std   Logger = Default()
+)
+
+// SetDefault replaces the logger used by the package-level logging
+// functions. The functions are safe for concurrent use as long as l
+// is. A nil l discards everything but Panic and Fatal.
+func SetDefault(l Logger) {
+	l = OrNull(l)
+	stdMu.Lock()
+	defer stdMu.Unlock()
+	std = l
+}
+
+// current returns the logger used by the package-level functions.
+func current() Logger {
+	stdMu.RLock()
+	defer stdMu.RUnlock()
+	return std
+}
+
+// Debug calls Debug on the default logger.
+func Debug(format string, v ...interface{}) {
+	current().Debug(format, v...)
+}
+
+// Info calls Info on the default logger.
+func Info(format string, v ...interface{}) {
+	current().Info(format, v...)
+}
+
+// Warning calls Warning on the default logger.
+func Warning(format string, v ...interface{}) {
+	current().Warning(format, v...)
+}
+
+// Error calls Error on the default logger.
+func Error(format string, v ...interface{}) {
+	current().Error(format, v...)
+}
+
+// Panic calls Panic on the default logger.
+func Panic(format string, v ...interface{}) {
+	current().Panic(format, v...)
+}
+
+// Fatal calls Fatal on the default logger.
+func Fatal(format string, v ...interface{}) {
+	current().Fatal(format, v...)
+}
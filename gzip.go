@@ -0,0 +1,62 @@
+package log
+
+import "io"
+import "sync"
+import "compress/gzip"
+
+// A GzipWriter compresses everything written to it onto another
+// writer, such as a file, for archiving high-volume logs. It is safe
+// for concurrent use. The stream is only readable to the end once
+// flushed or closed; a DefaultLogger flushes it, through Sync,
+// before panicking or exiting.
+type GzipWriter struct {
+	mu sync.Mutex
+	zw *gzip.Writer
+	w  io.Writer
+}
+
+// NewGzipWriter returns a GzipWriter compressing onto w.
+func NewGzipWriter(w io.Writer) *GzipWriter {
+	return &GzipWriter{zw: gzip.NewWriter(w), w: w}
+}
+
+// Write compresses p onto the underlying writer.
+func (g *GzipWriter) Write(p []byte) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.zw.Write(p)
+}
+
+// Flush writes any pending compressed data to the underlying writer,
+// so that a reader can decompress everything written so far.
+func (g *GzipWriter) Flush() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.zw.Flush()
+}
+
+// Sync flushes, and then syncs the underlying writer to storage if
+// it can be.
+func (g *GzipWriter) Sync() error {
+	if err := g.Flush(); err != nil {
+		return err
+	}
+	if s, ok := g.w.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Close finishes the gzip stream, and then closes the underlying
+// writer if it is an io.Closer.
+func (g *GzipWriter) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	err := g.zw.Close()
+	if c, ok := g.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+package log
+
+import "io"
+import "bytes"
+import "testing"
+import "strings"
+import "compress/gzip"
+
+// gunzip decompresses p, returning what could be read and whether
+// the stream ended properly.
+func gunzip(t *testing.T, p []byte) (string, error) {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(p))
+	if err != nil {
+		return "", err
+	}
+	b, err := io.ReadAll(zr)
+	return string(b), err
+}
+
+func TestGzipWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewGzipWriter(&out)
+	l := NewWriter(w)
+	l.SetClock(FixedClock(testTime))
+	for _, msg := range []string{"one", "two", "three"} {
+		l.Info("%s", msg)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := gunzip(t, out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2006-01-02T15:04:05Z\tINFO\t\tone\n" +
+		"2006-01-02T15:04:05Z\tINFO\t\ttwo\n" +
+		"2006-01-02T15:04:05Z\tINFO\t\tthree\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGzipWriterPanicFlushes(t *testing.T) {
+	var out bytes.Buffer
+	l := NewWriter(NewGzipWriter(&out))
+	l.Info("before")
+	catchPanic(func() { l.Panic("boom") })
+
+	// the stream isn't finished, but everything up to the panic can
+	// be read
+	got, err := gunzip(t, out.Bytes())
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("got error %v, want io.ErrUnexpectedEOF", err)
+	}
+	if !strings.Contains(got, "\tbefore\n") || !strings.HasSuffix(got, "\tboom\n") {
+		t.Errorf("got %q, want both lines", got)
+	}
+}
+
+func TestGzipWriterClosesUnderlying(t *testing.T) {
+	under := &closeRecorder{}
+	if err := NewGzipWriter(under).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if under.closed != 1 {
+		t.Errorf("closed %d times, want 1", under.closed)
+	}
+}
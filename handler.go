@@ -0,0 +1,38 @@
+package log
+
+import "io"
+import "fmt"
+import "net/http"
+
+// NewLevelHandler returns an HTTP handler for checking and changing
+// l's threshold on a live service, like slog's LevelVar. A GET
+// responds with the current level, such as "INFO". A PUT or POST
+// with a level name as its body, as understood by ParseLevel, sets
+// the threshold and responds with the new level, or with 400 Bad
+// Request if the name isn't valid. Since the threshold is shared,
+// the change applies to every logger derived from l too.
+func NewLevelHandler(l *DefaultLogger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut, http.MethodPost:
+			body, err := io.ReadAll(io.LimitReader(r.Body, 64))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			level, err := ParseLevel(string(body))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			l.SetLevel(level)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, l.Level())
+	})
+}
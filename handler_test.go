@@ -0,0 +1,45 @@
+package log
+
+import "strings"
+import "testing"
+import "net/http"
+import "net/http/httptest"
+
+func TestLevelHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		code   int
+		resp   string
+		level  Level
+	}{
+		{"get", http.MethodGet, "", http.StatusOK, "INFO\n", InfoLevel},
+		{"put", http.MethodPut, "warning", http.StatusOK, "WARN\n", WarningLevel},
+		{"post", http.MethodPost, "debug", http.StatusOK, "DEBUG\n", DebugLevel},
+		{"invalid", http.MethodPut, "loud", http.StatusBadRequest, "", InfoLevel},
+		{"empty", http.MethodPut, "", http.StatusBadRequest, "", InfoLevel},
+		{"method", http.MethodDelete, "", http.StatusMethodNotAllowed, "method not allowed\n", InfoLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _ := newLogger()
+			pl := l.Prefix("p")
+			h := NewLevelHandler(l)
+			req := httptest.NewRequest(tt.method, "/debug/level", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.code {
+				t.Errorf("got status %d, want %d", rec.Code, tt.code)
+			}
+			if tt.resp != "" && rec.Body.String() != tt.resp {
+				t.Errorf("got body %q, want %q", rec.Body.String(), tt.resp)
+			}
+			// the threshold is shared with derived loggers
+			if got := pl.(*DefaultLogger).Level(); got != tt.level {
+				t.Errorf("got level %v, want %v", got, tt.level)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package log
+
+import "sync"
+import "time"
+
+// A HeartbeatLogger forwards to another Logger, and writes a "still
+// alive" line at InfoLevel whenever nothing has been written for a
+// while, so that a quiet but healthy service can be told apart from
+// a hung one.
+type HeartbeatLogger struct {
+	l  Logger
+	hb *heartbeat
+}
+
+// heartbeat is the state shared by a tree of HeartbeatLoggers.
+type heartbeat struct {
+	mu       sync.Mutex
+	l        Logger
+	interval time.Duration
+	clock    Clock
+	last     time.Time
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// Heartbeat returns a logger that forwards to l, and checks every
+// interval whether anything was written in the last interval,
+// writing a heartbeat line to l if not. Only lines l is enabled for
+// count as written. Call Close to stop the checks. Loggers derived
+// via Prefix and With share the heartbeat, which is always written
+// to l itself.
+func Heartbeat(l Logger, interval time.Duration) *HeartbeatLogger {
+	t := time.NewTicker(interval)
+	return newHeartbeat(l, interval, t.C, t.Stop)
+}
+
+// newHeartbeat returns a HeartbeatLogger that checks for silence
+// whenever tick delivers, and calls stopTick once it stops
+// listening, so that tests can drive the checks.
+func newHeartbeat(l Logger, interval time.Duration, tick <-chan time.Time, stopTick func()) *HeartbeatLogger {
+	hb := &heartbeat{
+		l:        l,
+		interval: interval,
+		last:     time.Now(),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go hb.run(tick, stopTick)
+	return &HeartbeatLogger{l: l, hb: hb}
+}
+
+// SetClock replaces the system clock used to measure the silence.
+func (h *HeartbeatLogger) SetClock(c Clock) {
+	h.hb.mu.Lock()
+	defer h.hb.mu.Unlock()
+	h.hb.clock = c
+	h.hb.last = c.Now()
+}
+
+// now returns the current time. The caller must hold hb.mu.
+func (hb *heartbeat) now() time.Time {
+	if hb.clock == nil {
+		return time.Now()
+	}
+	return hb.clock.Now()
+}
+
+func (hb *heartbeat) run(tick <-chan time.Time, stopTick func()) {
+	defer close(hb.done)
+	defer stopTick()
+	for {
+		select {
+		case <-tick:
+			hb.check()
+		case <-hb.stop:
+			return
+		}
+	}
+}
+
+// check writes a heartbeat if the logger has been silent for the
+// interval.
+func (hb *heartbeat) check() {
+	hb.mu.Lock()
+	now := hb.now()
+	silent := now.Sub(hb.last) >= hb.interval
+	if silent {
+		hb.last = now
+	}
+	hb.mu.Unlock()
+	if silent {
+		hb.l.Info("still alive")
+	}
+}
+
+// touch notes that a line was just written.
+func (hb *heartbeat) touch() {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	hb.last = hb.now()
+}
+
+// Close stops the heartbeat.
+func (h *HeartbeatLogger) Close() error {
+	h.hb.once.Do(func() { close(h.hb.stop) })
+	<-h.hb.done
+	return nil
+}
+
+// Log forwards to the wrapped logger, and if it is enabled for
+// level, puts off the next heartbeat.
+func (h *HeartbeatLogger) Log(level Level, format string, v ...interface{}) {
+	if h.l.Enabled(level) {
+		h.hb.touch()
+	}
+	h.l.Log(level, format, v...)
+}
+
+// Debug forwards to the wrapped logger.
+func (h *HeartbeatLogger) Debug(format string, v ...interface{}) {
+	h.Log(DebugLevel, format, v...)
+}
+
+// Info forwards to the wrapped logger.
+func (h *HeartbeatLogger) Info(format string, v ...interface{}) {
+	h.Log(InfoLevel, format, v...)
+}
+
+// Warning forwards to the wrapped logger.
+func (h *HeartbeatLogger) Warning(format string, v ...interface{}) {
+	h.Log(WarningLevel, format, v...)
+}
+
+// Error forwards to the wrapped logger.
+func (h *HeartbeatLogger) Error(format string, v ...interface{}) {
+	h.Log(ErrorLevel, format, v...)
+}
+
+// Panic forwards to the wrapped logger.
+func (h *HeartbeatLogger) Panic(format string, v ...interface{}) {
+	h.Log(PanicLevel, format, v...)
+}
+
+// Fatal forwards to the wrapped logger.
+func (h *HeartbeatLogger) Fatal(format string, v ...interface{}) {
+	h.Log(FatalLevel, format, v...)
+}
+
+// Enabled reports whether the wrapped logger is enabled for level.
+func (h *HeartbeatLogger) Enabled(level Level) bool {
+	return h.l.Enabled(level)
+}
+
+// Must forwards to the wrapped logger.
+func (h *HeartbeatLogger) Must(message string, err error) {
+	h.l.Must(message, err)
+}
+
+// Prefix returns a heartbeat logger around the wrapped logger's
+// Prefix, sharing h's heartbeat.
+func (h *HeartbeatLogger) Prefix(prefix string) Logger {
+	return &HeartbeatLogger{l: h.l.Prefix(prefix), hb: h.hb}
+}
+
+// With returns a heartbeat logger around the wrapped logger's With,
+// sharing h's heartbeat.
+func (h *HeartbeatLogger) With(key string, value interface{}) Logger {
+	return &HeartbeatLogger{l: h.l.With(key, value), hb: h.hb}
+}
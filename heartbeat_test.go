@@ -0,0 +1,79 @@
+package log
+
+import "time"
+import "testing"
+import "strings"
+
+// newTestHeartbeat returns a heartbeat around l on a test clock,
+// the clock, and a func that makes it check for silence.
+func newTestHeartbeat(t *testing.T, l Logger) (*HeartbeatLogger, *testClock, func()) {
+	t.Helper()
+	tick, check := newManualTick()
+	h := newHeartbeat(l, time.Minute, tick, func() {})
+	c := newTestClock()
+	h.SetClock(c)
+	t.Cleanup(func() { h.Close() })
+	return h, c, check
+}
+
+func TestHeartbeat(t *testing.T) {
+	const beat = "2006-01-02T15:04:05Z\tINFO\t\tstill alive"
+	const line = "2006-01-02T15:04:05Z\tINFO\tp\thello"
+	tests := []struct {
+		name string
+		run  func(h *HeartbeatLogger, c *testClock, check func())
+		want []string
+	}{
+		{"silent", func(h *HeartbeatLogger, c *testClock, check func()) {
+			c.Advance(time.Minute)
+			check()
+		}, []string{beat}},
+		{"not silent long enough", func(h *HeartbeatLogger, c *testClock, check func()) {
+			c.Advance(59 * time.Second)
+			check()
+		}, nil},
+		{"logging puts it off", func(h *HeartbeatLogger, c *testClock, check func()) {
+			c.Advance(30 * time.Second)
+			h.Prefix("p").Info("hello")
+			c.Advance(30 * time.Second)
+			check()
+			c.Advance(30 * time.Second)
+			check()
+		}, []string{line, beat}},
+		{"disabled lines don't count", func(h *HeartbeatLogger, c *testClock, check func()) {
+			c.Advance(30 * time.Second)
+			h.Debug("hidden")
+			c.Advance(30 * time.Second)
+			check()
+		}, []string{beat}},
+		{"once per silence", func(h *HeartbeatLogger, c *testClock, check func()) {
+			c.Advance(time.Minute)
+			check()
+			c.Advance(30 * time.Second)
+			check()
+			c.Advance(30 * time.Second)
+			check()
+		}, []string{beat, beat}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			h, c, check := newTestHeartbeat(t, l)
+			tt.run(h, c, check)
+			if got := buf.Lines(); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeartbeatClose(t *testing.T) {
+	stopped := false
+	h := newHeartbeat(NewTest(), time.Minute, make(chan time.Time), func() { stopped = true })
+	h.Close()
+	if !stopped {
+		t.Error("the ticker wasn't stopped")
+	}
+	// closing again is harmless
+	h.Close()
+}
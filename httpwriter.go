@@ -0,0 +1,274 @@
+package log
+
+import "fmt"
+import "sync"
+import "time"
+import "bytes"
+import "net/http"
+
+// An HTTPWriter ships lines to a collector, POSTing them in batches
+// from a background goroutine so that logging calls never wait on
+// the network. Each request body is the batch's lines, one after the
+// other, just as they were written. It is safe for concurrent use.
+//
+// Batches that fail with a network error, 429 Too Many Requests or a
+// 5xx status are retried, with backoff, and dropped once the retries
+// run out; other statuses drop the batch at once. While the
+// collector is slow or down, batches queue up to a fixed limit, and
+// then the full policy applies: BlockWhenFull, the default, holds up
+// the logging calls until there is room, so nothing is lost, while
+// DropWhenFull drops new batches instead.
+type HTTPWriter struct {
+	url  string
+	size int
+
+	mu      sync.Mutex
+	lines   int
+	batch   []byte
+	client  *http.Client
+	retries int
+	policy  FullPolicy
+	err     error
+
+	// qmu guards sending to the queue against closing it
+	qmu    sync.RWMutex
+	closed bool
+	queue  chan httpBatch
+	stop   chan struct{}
+	done   chan struct{}
+	sent   chan struct{}
+	once   sync.Once
+}
+
+// httpBatch is a queued request body, or if flushed is set, a marker
+// that is closed once every batch queued before it has been sent.
+type httpBatch struct {
+	body    []byte
+	flushed chan struct{}
+}
+
+// httpQueueSize is how many batches may wait to be sent.
+const httpQueueSize = 16
+
+// NewHTTPWriter returns a writer that POSTs lines to url in batches
+// of size lines, and at least every interval while lines are
+// waiting. It uses http.DefaultClient, and retries a failed batch 3
+// times, unless changed with SetClient and SetRetries. Call Close to
+// send the final batch and stop the background goroutines.
+func NewHTTPWriter(url string, size int, interval time.Duration) *HTTPWriter {
+	h := &HTTPWriter{
+		url:     url,
+		size:    size,
+		client:  http.DefaultClient,
+		retries: 3,
+		queue:   make(chan httpBatch, httpQueueSize),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		sent:    make(chan struct{}),
+	}
+	go h.tick(interval)
+	go h.send()
+	return h
+}
+
+// SetClient sets the client used for the requests.
+func (h *HTTPWriter) SetClient(c *http.Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.client = c
+}
+
+// SetRetries sets how many times a failed batch is retried before
+// it is dropped.
+func (h *HTTPWriter) SetRetries(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.retries = n
+}
+
+// SetFullPolicy sets what happens to a batch when the queue is full.
+func (h *HTTPWriter) SetFullPolicy(policy FullPolicy) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.policy = policy
+}
+
+// Write adds p to the batch as a line, queueing the batch to be sent
+// if that fills it. An error sending an earlier batch is returned by
+// the next call to Write, Flush or Close.
+func (h *HTTPWriter) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	if err := h.takeErr(); err != nil {
+		h.mu.Unlock()
+		return 0, err
+	}
+	h.batch = append(h.batch, p...)
+	h.lines++
+	var b httpBatch
+	if h.lines >= h.size {
+		b = h.take()
+	}
+	block := h.policy == BlockWhenFull
+	h.mu.Unlock()
+
+	if b.body != nil {
+		h.push(b, block)
+	}
+	return len(p), nil
+}
+
+// take removes the batch, to be queued. The caller must hold h.mu.
+func (h *HTTPWriter) take() httpBatch {
+	b := httpBatch{body: h.batch}
+	h.batch = nil
+	h.lines = 0
+	return b
+}
+
+// push queues b, waiting for room if block is set and otherwise
+// dropping it if the queue is full, and reports whether it was
+// queued. Nothing is queued once the writer is closed.
+func (h *HTTPWriter) push(b httpBatch, block bool) bool {
+	h.qmu.RLock()
+	defer h.qmu.RUnlock()
+	if h.closed {
+		return false
+	}
+	if block {
+		h.queue <- b
+		return true
+	}
+	select {
+	case h.queue <- b:
+		return true
+	default:
+		return false
+	}
+}
+
+// takeErr returns and clears the kept error. The caller must hold
+// h.mu.
+func (h *HTTPWriter) takeErr() error {
+	err := h.err
+	h.err = nil
+	return err
+}
+
+func (h *HTTPWriter) tick(interval time.Duration) {
+	defer close(h.done)
+	if interval <= 0 {
+		<-h.stop
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			h.mu.Lock()
+			b := h.take()
+			block := h.policy == BlockWhenFull
+			h.mu.Unlock()
+			if b.body != nil {
+				h.push(b, block)
+			}
+		case <-h.stop:
+			return
+		}
+	}
+}
+
+func (h *HTTPWriter) send() {
+	defer close(h.sent)
+	for b := range h.queue {
+		if b.flushed != nil {
+			close(b.flushed)
+			continue
+		}
+		if err := h.post(b.body); err != nil {
+			h.mu.Lock()
+			h.err = err
+			h.mu.Unlock()
+		}
+	}
+}
+
+// post sends body, retrying transient failures with backoff.
+func (h *HTTPWriter) post(body []byte) error {
+	h.mu.Lock()
+	client, retries := h.client, h.retries
+	h.mu.Unlock()
+
+	backoff := 100 * time.Millisecond
+	var err error
+	for try := 0; ; try++ {
+		var retry bool
+		retry, err = h.postOnce(client, body)
+		if err == nil || !retry || try >= retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// postOnce sends body, and reports whether a failure is worth
+// retrying.
+func (h *HTTPWriter) postOnce(client *http.Client, body []byte) (retry bool, err error) {
+	resp, err := client.Post(h.url, "text/plain; charset=utf-8", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("log: POST %s: %s", h.url, resp.Status)
+	}
+	return false, fmt.Errorf("log: POST %s: %s", h.url, resp.Status)
+}
+
+// Flush queues any waiting lines, and then waits until every batch
+// queued so far has been sent or dropped.
+func (h *HTTPWriter) Flush() error {
+	h.mu.Lock()
+	b := h.take()
+	h.mu.Unlock()
+	if b.body != nil {
+		h.push(b, true)
+	}
+
+	flushed := make(chan struct{})
+	if h.push(httpBatch{flushed: flushed}, true) {
+		<-flushed
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.takeErr()
+}
+
+// Sync is Flush, so that a DefaultLogger sends the waiting lines
+// before panicking or exiting.
+func (h *HTTPWriter) Sync() error {
+	return h.Flush()
+}
+
+// Close sends the final batch, waits for the queue to be sent, and
+// stops the background goroutines. Lines written afterwards are
+// dropped.
+func (h *HTTPWriter) Close() error {
+	var err error
+	h.once.Do(func() {
+		close(h.stop)
+		<-h.done
+		err = h.Flush()
+		h.qmu.Lock()
+		h.closed = true
+		close(h.queue)
+		h.qmu.Unlock()
+		<-h.sent
+	})
+	return err
+}
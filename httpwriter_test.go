@@ -0,0 +1,160 @@
+package log
+
+import "io"
+import "sync"
+import "time"
+import "testing"
+import "strings"
+import "net/http"
+import "net/http/httptest"
+
+// collector is a test log collector, recording the body of each
+// request it answers with the next of its statuses, or 200 once
+// they run out.
+type collector struct {
+	mu       sync.Mutex
+	bodies   []string
+	statuses []int
+	hold     chan struct{} // if set, requests wait for it to close
+	started  chan struct{} // if set, is sent to as each request arrives
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if c.started != nil {
+		c.started <- struct{}{}
+	}
+	if c.hold != nil {
+		<-c.hold
+	}
+	body, _ := io.ReadAll(r.Body)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bodies = append(c.bodies, string(body))
+	if len(c.statuses) > 0 {
+		w.WriteHeader(c.statuses[0])
+		c.statuses = c.statuses[1:]
+	}
+}
+
+func (c *collector) received() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.bodies...)
+}
+
+// newCollector starts a server for c, closed with the test.
+func newCollector(t *testing.T, c *collector) *httptest.Server {
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTPWriterBatches(t *testing.T) {
+	c := &collector{}
+	srv := newCollector(t, c)
+	h := NewHTTPWriter(srv.URL, 2, 0)
+	l := NewWriter(h)
+	l.SetTimeFormat("-")
+	for _, msg := range []string{"one", "two", "three"} {
+		l.Info("%s", msg)
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"-\tINFO\t\tone\n-\tINFO\t\ttwo\n", "-\tINFO\t\tthree\n"}
+	if got := c.received(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHTTPWriterInterval(t *testing.T) {
+	c := &collector{}
+	srv := newCollector(t, c)
+	h := NewHTTPWriter(srv.URL, 100, time.Millisecond)
+	defer h.Close()
+	h.Write([]byte("one\n"))
+	for deadline := time.Now().Add(5 * time.Second); len(c.received()) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("batch never sent")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := c.received(); got[0] != "one\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestHTTPWriterRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		requests int
+		fails    bool
+	}{
+		{"transient", []int{503}, 1, 2, false},
+		{"too many requests", []int{429}, 1, 2, false},
+		{"retries run out", []int{500, 500}, 1, 2, true},
+		{"not retried", []int{400}, 3, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &collector{statuses: tt.statuses}
+			srv := newCollector(t, c)
+			h := NewHTTPWriter(srv.URL, 1, 0)
+			h.SetRetries(tt.retries)
+			defer h.Close()
+			h.Write([]byte("one\n"))
+			if err := h.Flush(); (err != nil) != tt.fails {
+				t.Errorf("Flush = %v, want failure %v", err, tt.fails)
+			}
+			if n := len(c.received()); n != tt.requests {
+				t.Errorf("got %d requests, want %d", n, tt.requests)
+			}
+		})
+	}
+}
+
+func TestHTTPWriterDoesNotWait(t *testing.T) {
+	c := &collector{hold: make(chan struct{}), started: make(chan struct{}, 1)}
+	srv := newCollector(t, c)
+	h := NewHTTPWriter(srv.URL, 1, 0)
+	h.Write([]byte("one\n"))
+	<-c.started
+	// the collector is stuck on the first batch, yet writing goes on
+	done := make(chan struct{})
+	go func() {
+		h.Write([]byte("two\n"))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write waited for the collector")
+	}
+	close(c.hold)
+	h.Close()
+}
+
+func TestHTTPWriterDropWhenFull(t *testing.T) {
+	c := &collector{hold: make(chan struct{}), started: make(chan struct{}, 1)}
+	srv := newCollector(t, c)
+	h := NewHTTPWriter(srv.URL, 1, 0)
+	h.SetFullPolicy(DropWhenFull)
+	h.Write([]byte("sending\n"))
+	<-c.started
+	for i := 0; i < httpQueueSize+5; i++ {
+		h.Write([]byte("queued\n"))
+	}
+	// let the rest through without blocking on started
+	go func() {
+		for range c.started {
+		}
+	}()
+	close(c.hold)
+	h.Close()
+	close(c.started)
+	if n := len(c.received()); n != 1+httpQueueSize {
+		t.Errorf("got %d requests, want %d", n, 1+httpQueueSize)
+	}
+}
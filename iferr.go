@@ -0,0 +1,31 @@
+package log
+
+import "errors"
+import "context"
+
+// LogIfErr logs err to l at level if err is not nil, and returns
+// err either way, so that it fits in a return statement:
+//
+//	return LogIfErr(l, ErrorLevel, doThing())
+func LogIfErr(l Logger, level Level, err error) error {
+	if err != nil {
+		l.Log(level, "%v", err)
+	}
+	return err
+}
+
+// LogIfErrContext is like LogIfErr, but says so when err comes from
+// a context, telling a context that was canceled apart from one
+// whose deadline passed.
+func LogIfErrContext(l Logger, level Level, err error) error {
+	switch {
+	case err == nil:
+	case errors.Is(err, context.Canceled):
+		l.Log(level, "Canceled: %v", err)
+	case errors.Is(err, context.DeadlineExceeded):
+		l.Log(level, "Deadline exceeded: %v", err)
+	default:
+		l.Log(level, "%v", err)
+	}
+	return err
+}
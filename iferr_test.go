@@ -0,0 +1,46 @@
+package log
+
+import "fmt"
+import "errors"
+import "context"
+import "testing"
+
+func TestLogIfErr(t *testing.T) {
+	errBroken := errors.New("broken pipe")
+	wrapped := fmt.Errorf("fetch: %w", context.DeadlineExceeded)
+	tests := []struct {
+		name    string
+		err     error
+		want    string // logged by LogIfErr, or "" for nothing
+		wantCtx string // logged by LogIfErrContext
+	}{
+		{"nil", nil, "", ""},
+		{"generic", errBroken, "broken pipe", "broken pipe"},
+		{"canceled", context.Canceled, "context canceled", "Canceled: context canceled"},
+		{"deadline", context.DeadlineExceeded, "context deadline exceeded", "Deadline exceeded: context deadline exceeded"},
+		{"wrapped deadline", wrapped, "fetch: context deadline exceeded", "Deadline exceeded: fetch: context deadline exceeded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, f := range []struct {
+				log  func(l Logger, level Level, err error) error
+				want string
+			}{{LogIfErr, tt.want}, {LogIfErrContext, tt.wantCtx}} {
+				tl := NewTest()
+				if err := f.log(tl, WarningLevel, tt.err); err != tt.err {
+					t.Errorf("returned %v, want the error unchanged", err)
+				}
+				got := tl.Messages()
+				if f.want == "" {
+					if len(got) != 0 {
+						t.Errorf("logged %+v for a nil error", got)
+					}
+					continue
+				}
+				if len(got) != 1 || got[0].Level != WarningLevel || got[0].Message() != f.want {
+					t.Errorf("logged %+v, want %q", got, f.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package log
+
+import "io"
+import "fmt"
+import "bytes"
+import "encoding/json"
+
+// NewJSON returns a logger that writes one JSON object per line to
+// w, with the fields time, level, prefix and msg, plus any fields
+// added with With.
+func NewJSON(w io.Writer) (l *DefaultLogger) {
+	l = NewWriter(w)
+	l.format = formatJSON
+	return
+}
+
+// jsonLine renders e as a JSON object, with the keys severity,
+// time, level, prefix, caller, msg, the fields in the order they
+// were added, and then stack. The severity, time, caller and stack
+// keys are only present if enabled.
+func jsonLine(e entry) []byte {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	if e.severity {
+		jsonPair(&b, "severity", e.level.Severity())
+	}
+	if e.time != "" {
+		jsonPair(&b, "time", e.time)
+	}
+	jsonPair(&b, "level", e.level.String())
+	jsonPair(&b, "prefix", e.prefix)
+	if e.caller != "" {
+		jsonPair(&b, "caller", e.caller)
+	}
+	jsonPair(&b, "msg", e.msg)
+	// the standard keys win over any field that reuses them
+	jsonFields(&b, e.fields, func(key string) bool {
+		switch key {
+		case "level", "prefix", "msg":
+			return true
+		case "severity":
+			return e.severity
+		case "time":
+			return e.time != ""
+		case "caller":
+			return e.caller != ""
+		case "stack":
+			return e.stack != ""
+		}
+		return false
+	})
+	if e.stack != "" {
+		jsonPair(&b, "stack", e.stack)
+	}
+	b.WriteByte('}')
+	return b.Bytes()
+}
+
+// jsonFields writes the fields to b as JSON pairs, in the order they
+// were added, except those whose keys are reserved. Of several
+// fields with the same key, only the last is written, in its place.
+func jsonFields(b *bytes.Buffer, fields []field, reserved func(key string) bool) {
+	last := make(map[string]int, len(fields))
+	for i, f := range fields {
+		last[f.jsonKey()] = i
+	}
+	for i, f := range fields {
+		key := f.jsonKey()
+		if last[key] == i && !reserved(key) {
+			jsonPair(b, key, jsonValue(f.value))
+		}
+	}
+}
+
+// jsonPair writes "key":value to b, after a comma unless it is the
+// first pair in the object.
+func jsonPair(b *bytes.Buffer, key string, value interface{}) {
+	if b.Len() > 0 && b.Bytes()[b.Len()-1] != '{' {
+		b.WriteByte(',')
+	}
+	k, _ := json.Marshal(key)
+	b.Write(k)
+	b.WriteByte(':')
+	v, err := json.Marshal(value)
+	if err != nil {
+		// jsonValue only lets through values that marshal, so
+		// this can't really happen
+		panic(fmt.Sprintf("Failed to encode log!\nError: %v\nKey: %s\n", err, key))
+	}
+	b.Write(v)
+}
+
+// jsonValue returns v in a form that encoding/json can marshal
+// usefully. Errors become their message rather than an empty
+// object, and anything that can't be marshaled is formatted with
+// fmt instead.
+func jsonValue(v interface{}) interface{} {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return json.RawMessage(b)
+}
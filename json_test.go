@@ -0,0 +1,50 @@
+package log
+
+import "testing"
+import "encoding/json"
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix []string
+		msg    string
+		want   string
+	}{
+		{"plain", nil, "hello", ""},
+		{"quotes", []string{"db"}, `say "hi" to C:\path`, "db"},
+		{"newlines", []string{"http", "access"}, "line one\nline two\r\n", "http:access"},
+		{"control", nil, "bell\a tab\t nul\x00", ""},
+		{"unicode", []string{"ünï"}, "snow ☃ and <html> & friends", "ünï"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &CaptureBuffer{}
+			l := NewJSON(buf)
+			l.SetClock(FixedClock(testTime))
+			var pl Logger = l
+			for _, p := range tt.prefix {
+				pl = pl.Prefix(p)
+			}
+			pl.With("n", 3).Info("%s", tt.msg)
+
+			var got struct {
+				Time   string `json:"time"`
+				Level  string `json:"level"`
+				Prefix string `json:"prefix"`
+				Msg    string `json:"msg"`
+				N      int    `json:"n"`
+			}
+			lines := buf.Lines()
+			if len(lines) != 1 {
+				t.Fatalf("got %d lines, want 1: %q", len(lines), buf.String())
+			}
+			if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+				t.Fatalf("invalid JSON %q: %v", lines[0], err)
+			}
+			if got.Time != "2006-01-02T15:04:05Z" || got.Level != "INFO" ||
+				got.Prefix != tt.want || got.Msg != tt.msg || got.N != 3 {
+				t.Errorf("got %+v from %q", got, lines[0])
+			}
+		})
+	}
+}
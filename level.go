@@ -0,0 +1,167 @@
+package log
+
+import "fmt"
+import "sync"
+import "strings"
+import "sync/atomic"
+
+// A Level is the severity of a log line. Levels are ordered, so a
+// logger's threshold suppresses every level below it.
+type Level int
+
+// The levels, from least to most severe. The zero Level is
+// InfoLevel, which is the default threshold.
+//
+// TraceLevel is for firehose diagnostics such as per-iteration
+// logging. DefaultLogger already has a Trace field, for tracing
+// Must, so there is no Trace method: use Log(TraceLevel, ...).
+const (
+	TraceLevel Level = iota - 2
+	DebugLevel
+	InfoLevel
+	WarningLevel
+	ErrorLevel
+	PanicLevel
+	FatalLevel
+)
+
+// String returns the tag written in the level column, such as INFO,
+// or the name registered for the level with RegisterLevelName.
+func (lv Level) String() string {
+	if name, ok := loadLevelNames().tags[lv]; ok {
+		return name
+	}
+	switch lv {
+	case TraceLevel:
+		return "TRACE"
+	case DebugLevel:
+		return "DEBUG"
+	case InfoLevel:
+		return "INFO"
+	case WarningLevel:
+		return "WARN"
+	case ErrorLevel:
+		return "ERROR"
+	case PanicLevel:
+		return "PANIC"
+	case FatalLevel:
+		return "FATAL"
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(lv))
+}
+
+// Severity returns the syslog severity, from 0 for emergencies to 7
+// for debugging, nearest to lv: TraceLevel and DebugLevel are 7,
+// InfoLevel 6, WarningLevel 4, ErrorLevel 3, and PanicLevel and
+// FatalLevel 2, for critical, which is also how NewSyslog writes
+// them.
+func (lv Level) Severity() int {
+	switch {
+	case lv >= PanicLevel:
+		return 2
+	case lv >= ErrorLevel:
+		return 3
+	case lv >= WarningLevel:
+		return 4
+	case lv >= InfoLevel:
+		return 6
+	}
+	return 7
+}
+
+// ParseLevel returns the Level named by s, which is one of the tags
+// that String returns, the full name for WARN, "warning", or a name
+// registered with RegisterLevelName. Case is ignored.
+func ParseLevel(s string) (Level, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	if level, ok := loadLevelNames().levels[name]; ok {
+		return level, nil
+	}
+	switch name {
+	case "TRACE":
+		return TraceLevel, nil
+	case "DEBUG":
+		return DebugLevel, nil
+	case "INFO":
+		return InfoLevel, nil
+	case "WARN", "WARNING":
+		return WarningLevel, nil
+	case "ERROR":
+		return ErrorLevel, nil
+	case "PANIC":
+		return PanicLevel, nil
+	case "FATAL":
+		return FatalLevel, nil
+	}
+	return InfoLevel, fmt.Errorf("log: unknown level %q", s)
+}
+
+// levelNames is the registry behind RegisterLevelName. It is
+// replaced rather than changed, so that String and ParseLevel can
+// read it without locking.
+type levelNames struct {
+	tags   map[Level]string
+	levels map[string]Level
+}
+
+var (
+	levelNamesMu sync.Mutex
+	levelNamesV  atomic.Value
+)
+
+func loadLevelNames() levelNames {
+	names, _ := levelNamesV.Load().(levelNames)
+	return names
+}
+
+// RegisterLevelName adds name as an alias for level, to match the
+// vocabulary of an existing log pipeline: ParseLevel and
+// SetLevelByName accept it, and String, and so the level column,
+// prints it upper-cased in place of the standard tag, as in
+//
+//	RegisterLevelName("notice", InfoLevel)
+//
+// The standard names are still accepted. If several names are
+// registered for a level, the last one is printed. It is meant to be
+// called during initialization, but is safe to call at any time.
+func RegisterLevelName(name string, level Level) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+
+	levelNamesMu.Lock()
+	defer levelNamesMu.Unlock()
+	old := loadLevelNames()
+	names := levelNames{
+		tags:   make(map[Level]string, len(old.tags)+1),
+		levels: make(map[string]Level, len(old.levels)+1),
+	}
+	for lv, tag := range old.tags {
+		names.tags[lv] = tag
+	}
+	for n, lv := range old.levels {
+		names.levels[n] = lv
+	}
+	names.tags[level] = name
+	names.levels[name] = level
+	levelNamesV.Store(names)
+}
+
+// A levelVar holds a threshold that may change while loggers are
+// using it. A nil levelVar holds InfoLevel.
+type levelVar struct {
+	v int32
+}
+
+func (lv *levelVar) get() Level {
+	if lv == nil {
+		return InfoLevel
+	}
+	return Level(atomic.LoadInt32(&lv.v))
+}
+
+func (lv *levelVar) set(level Level) {
+	atomic.StoreInt32(&lv.v, int32(level))
+}
+
+func (lv *levelVar) swap(level Level) Level {
+	return Level(atomic.SwapInt32(&lv.v, int32(level)))
+}
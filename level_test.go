@@ -0,0 +1,98 @@
+package log
+
+import "testing"
+
+// restoreLevelNames puts back the registered level names once the
+// test is over.
+func restoreLevelNames(t *testing.T) {
+	old := loadLevelNames()
+	t.Cleanup(func() {
+		levelNamesMu.Lock()
+		defer levelNamesMu.Unlock()
+		levelNamesV.Store(old)
+	})
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		s     string
+		level Level
+		ok    bool
+	}{
+		{"trace", TraceLevel, true},
+		{"DEBUG", DebugLevel, true},
+		{"Info", InfoLevel, true},
+		{"warn", WarningLevel, true},
+		{"warning", WarningLevel, true},
+		{" error\n", ErrorLevel, true},
+		{"panic", PanicLevel, true},
+		{"fatal", FatalLevel, true},
+		{"loud", InfoLevel, false},
+		{"", InfoLevel, false},
+	}
+	for _, tt := range tests {
+		level, err := ParseLevel(tt.s)
+		if level != tt.level || (err == nil) != tt.ok {
+			t.Errorf("ParseLevel(%q) = %v, %v", tt.s, level, err)
+		}
+		if tt.ok {
+			// each tag parses back to its level
+			if back, err := ParseLevel(level.String()); back != level || err != nil {
+				t.Errorf("ParseLevel(%q) = %v, %v", level.String(), back, err)
+			}
+		}
+	}
+}
+
+func TestRegisterLevelName(t *testing.T) {
+	restoreLevelNames(t)
+	RegisterLevelName("notice", InfoLevel)
+	RegisterLevelName(" Critical ", ErrorLevel)
+
+	tests := []struct {
+		name  string
+		level Level
+		tag   string
+	}{
+		{"notice", InfoLevel, "NOTICE"},
+		{"NOTICE", InfoLevel, "NOTICE"},
+		{"critical", ErrorLevel, "CRITICAL"},
+		// the standard names still parse
+		{"info", InfoLevel, "NOTICE"},
+		{"warning", WarningLevel, "WARN"},
+	}
+	for _, tt := range tests {
+		level, err := ParseLevel(tt.name)
+		if level != tt.level || err != nil {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", tt.name, level, err, tt.level)
+		}
+		if got := level.String(); got != tt.tag {
+			t.Errorf("%q prints as %q, want %q", tt.name, got, tt.tag)
+		}
+	}
+
+	l, buf := newLogger()
+	if err := l.SetLevelByName("notice"); err != nil {
+		t.Fatal(err)
+	}
+	l.Debug("dropped")
+	l.Info("hello")
+	if want := "2006-01-02T15:04:05Z\tNOTICE\t\thello\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+	if err := l.SetLevelByName("loud"); err == nil || l.Level() != InfoLevel {
+		t.Errorf("SetLevelByName(loud) = %v, left level %v", err, l.Level())
+	}
+}
+
+func TestRegisterLevelNameLastWins(t *testing.T) {
+	restoreLevelNames(t)
+	RegisterLevelName("alert", ErrorLevel)
+	RegisterLevelName("severe", ErrorLevel)
+	if got := ErrorLevel.String(); got != "SEVERE" {
+		t.Errorf("got %q, want SEVERE", got)
+	}
+	if level, err := ParseLevel("alert"); level != ErrorLevel || err != nil {
+		t.Errorf("ParseLevel(alert) = %v, %v", level, err)
+	}
+}
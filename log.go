@@ -1,67 +1,1098 @@
 package log
 
+import "io"
 import "os"
 import "fmt"
+import "errors"
+import "sync"
+import "sync/atomic"
+import "reflect"
 import "time"
+import "strings"
+import "strconv"
+import "unicode/utf8"
 
 // A Logger captures program events at varying severity levels, and
 // is relatively simple to nest to indicate logic structure.
+//
+// Prefix and With return a Logger rather than the concrete type, so
+// that code holding any Logger can nest it without type assertions:
+//
+//	var l Logger = Default()
+//	l = l.Prefix("worker")
+//
+// The derived logger is of the same concrete type as the original,
+// so a type assertion gets back to its settings if need be.
+//
+// Calls at a level the logger drops return before formatting, but
+// the arguments have already been evaluated and boxed by then. Where
+// building the arguments is expensive, guard the call with Enabled:
+//
+//	if l.Enabled(DebugLevel) {
+//		l.Debug("state: %s", dump(state))
+//	}
 type Logger interface {
+	Debug(format string, v ...interface{})
 	Info(format string, v ...interface{})
 	Warning(format string, v ...interface{})
+	Error(format string, v ...interface{})
 	Panic(format string, v ...interface{})
+	Fatal(format string, v ...interface{})
+	Log(level Level, format string, v ...interface{})
+	Enabled(level Level) bool
 	Must(message string, err error)
 	Prefix(prefix string) Logger
+	With(key string, value interface{}) Logger
 }
 
-// DefaultLogger is a simple logger that discards Info/Warning/Panic
-// metadata (aside from panicing on Panic() of course) and simply
-// writes the timestamped log data to stderr.
+// Every implementation in this package must satisfy Logger.
+var (
+	_ Logger = (*DefaultLogger)(nil)
+	_ Logger = (*NullLogger)(nil)
+	_ Logger = (*CountingNullLogger)(nil)
+	_ Logger = (*TestLogger)(nil)
+	_ Logger = (*RateLimitedLogger)(nil)
+	_ Logger = (*DedupLogger)(nil)
+	_ Logger = (*SampledLogger)(nil)
+	_ Logger = (*FilterLogger)(nil)
+	_ Logger = (*AsyncLogger)(nil)
+	_ Logger = (*RingLogger)(nil)
+	_ Logger = (*TBLogger)(nil)
+	_ Logger = (*HeartbeatLogger)(nil)
+	_ Logger = (*DeferredLogger)(nil)
+	_ Logger = multiLogger(nil)
+)
+
+// DefaultLogger is a simple logger that writes the timestamped log
+// data, tagged with its level, to stderr or to whichever io.Writer
+// it was given. Each line has the tab-separated columns time, level,
+// prefix and message. A DefaultLogger is safe for concurrent use,
+// and loggers derived via Prefix share its lock so that their lines
+// don't interleave either.
 type DefaultLogger struct {
 	prefix string
 	Trace  bool
+	w      *writerVar
+	level  *levelVar
+	mu     *sync.Mutex
+	format format
+	fields []field
+	group  string
+
+	timeFormat string
+	localTime  bool
+	clock      Clock
+	caller     bool
+	buf        *buffer
+	sep        string
+	onError    func(error)
+	color      bool
+	errW       io.Writer
+	levelW     map[Level]io.Writer
+	counts     *stats
+	hooks      []Hook
+	eol        string
+	eolSet     bool
+	pid        bool
+	hostname   string
+	corrID     string
+	seq        *uint64
+	redact     map[string]bool
+	severity   bool
+	header     *header
+	singleLine bool
+	crashW     io.Writer
+	uniqPrefix bool
+	flushLevel Level
+	maxMsg     int
+	panicStack bool
+	columns    []Column
+	noTime     bool
+	errDetail  bool
+	panicValue func(msg string) interface{}
+	omitPrefix bool
+	formatter  Formatter
+	start      time.Time
+
+	// owner is the logger that created the writer, and is the only
+	// one that may close it.
+	owner *DefaultLogger
+}
+
+// format selects how a DefaultLogger renders each line.
+type format int
+
+const (
+	formatText format = iota
+	formatJSON
+	formatLogfmt
+	formatCSV
+)
+
+// zeroMu guards writes from loggers that weren't made by a
+// constructor, which most likely all point at stderr anyway.
+var zeroMu sync.Mutex
+
+// osExit is called by Fatal, and is a variable so that tests can
+// stop it from ending the process.
+var osExit = os.Exit
+
+// A syncer is a writer that can flush its data to stable storage,
+// such as an *os.File.
+type syncer interface {
+	Sync() error
+}
+
+// A writerVar holds a writer that may be swapped while loggers are
+// using it. A nil writerVar holds nothing.
+type writerVar struct {
+	v atomic.Value
+}
+
+// boxedWriter wraps each writer stored in a writerVar, since an
+// atomic.Value only takes values of one concrete type.
+type boxedWriter struct {
+	io.Writer
+}
+
+func newWriterVar(w io.Writer) *writerVar {
+	wv := &writerVar{}
+	wv.set(w)
+	return wv
+}
+
+func (wv *writerVar) get() io.Writer {
+	if wv == nil {
+		return nil
+	}
+	b, _ := wv.v.Load().(boxedWriter)
+	return b.Writer
+}
+
+func (wv *writerVar) set(w io.Writer) {
+	wv.v.Store(boxedWriter{w})
 }
 
-// Default returns a logger suitable for writing to stderr.
+// Default returns a logger suitable for writing to stderr. Closing
+// it won't close stderr.
 func Default() (l *DefaultLogger) {
-	l = &DefaultLogger{}
+	l = NewWriter(os.Stderr)
+	l.owner = nil
+	return
+}
+
+// Named returns a stderr logger already carrying the prefix name,
+// just like Default().Prefix(name) but without losing the concrete
+// type.
+func Named(name string) (l *DefaultLogger) {
+	l = Default()
+	l.prefix = name
+	return
+}
+
+// NewWriter returns a logger that writes to w instead of stderr.
+// The logger owns w, so closing it closes w if w is an io.Closer.
+func NewWriter(w io.Writer) (l *DefaultLogger) {
+	l = &DefaultLogger{w: newWriterVar(w), mu: &sync.Mutex{}, level: &levelVar{}}
+	l.owner = l
 	return
 }
 
-func (l *DefaultLogger) out(t string) {
-	_, err := fmt.Fprintf(
-		os.Stderr,
-		"%s\t%s\t%s\n",
-		time.Now().UTC().Format(time.RFC3339),
-		l.prefix, t)
+// NewSplit returns a logger that writes Debug and Info lines to
+// infoW, and Warning and above to errW, as a CLI tool might with
+// stdout and stderr. SetOutput only changes infoW.
+func NewSplit(infoW, errW io.Writer) (l *DefaultLogger) {
+	l = NewWriter(infoW)
+	l.errW = errW
+	return
+}
 
+// Close stops any interval flushing, flushes any buffered lines,
+// and closes the writers if they are io.Closers. Only the logger
+// returned by a constructor owns its writer: on a logger derived
+// from it via Prefix or With, Close does nothing, so a subsystem
+// can't close the writer out from under everyone else.
+func (l *DefaultLogger) Close() error {
+	if l.owner != l {
+		return nil
+	}
+	w := l.writer()
+	var err error
+	if l.buf != nil {
+		l.buf.stopFlushing()
+		err = l.Flush()
+		w = l.buf.under
+	}
+	for _, w := range l.writers(w) {
+		if c, ok := w.(io.Closer); ok {
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
+
+// SetOutput changes the writer that l logs to, for l and every
+// logger derived from it via Prefix and With, before or after. It is
+// safe to call while other goroutines are logging: each line goes
+// wholly to the old writer or wholly to the new one.
+func (l *DefaultLogger) SetOutput(w io.Writer) {
+	if l.buf != nil {
+		l.buf.reset(l.mutex(), w)
+		return
+	}
+	if l.w == nil {
+		l.w = &writerVar{}
+	}
+	l.w.set(w)
+}
+
+// SetDebug enables or disables Debug output. It is disabled by
+// default. Enabling it lowers the threshold to DebugLevel, and
+// disabling it raises the threshold to InfoLevel if it was any
+// lower. Like SetLevel, it affects every logger sharing l's
+// threshold.
+func (l *DefaultLogger) SetDebug(enabled bool) {
+	if enabled {
+		l.SetLevel(DebugLevel)
+	} else if l.level.get() < InfoLevel {
+		l.SetLevel(InfoLevel)
+	}
+}
+
+// SetLevel sets the minimum level that l writes; calls below it
+// return without formatting their arguments. The default is
+// InfoLevel. Panic always panics, whether or not its line is
+// written.
+//
+// Loggers derived via Prefix and With share l's threshold, so that
+// turning up the verbosity of the root turns it up everywhere;
+// SetLevel on any of them changes it for all. It is safe to call
+// while other goroutines are logging.
+func (l *DefaultLogger) SetLevel(level Level) {
+	if l.level == nil {
+		l.level = &levelVar{}
+	}
+	l.level.set(level)
+}
+
+// SetLevelByName sets the threshold like SetLevel, to the level
+// named by name as understood by ParseLevel, including names added
+// with RegisterLevelName. It leaves the threshold alone and returns
+// an error if the name is unknown.
+func (l *DefaultLogger) SetLevelByName(name string) error {
+	level, err := ParseLevel(name)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to log to stderr!\nError: %v\nLog: %s\n", err, t))
+		return err
+	}
+	l.SetLevel(level)
+	return nil
+}
+
+// Level returns the minimum level that l writes, as set by
+// SetLevel.
+func (l *DefaultLogger) Level() Level {
+	return l.level.get()
+}
+
+// PushLevel sets the threshold to level until the returned function
+// is called, which restores the threshold it replaced, as in
+//
+//	defer l.PushLevel(WarningLevel)()
+//
+// to quiet a noisy operation. Since the threshold is shared with
+// derived loggers, they are quieted too. Pushes from several
+// goroutines at once are safe, but should be restored in reverse
+// order or the wrong threshold may be left behind.
+func (l *DefaultLogger) PushLevel(level Level) (restore func()) {
+	if l.level == nil {
+		l.level = &levelVar{}
+	}
+	old := l.level.swap(level)
+	return func() { l.level.set(old) }
+}
+
+// SetPrefixSeparator sets the string that Prefix puts between
+// segments, which is ":" by default. An empty sep restores the
+// default. Loggers derived via Prefix inherit the separator.
+func (l *DefaultLogger) SetPrefixSeparator(sep string) {
+	l.sep = sep
+}
+
+// SetDedupPrefix makes Prefix skip a segment identical to the one
+// it would follow, so that code that defensively prefixes again
+// gets "db:query" rather than "db:db:query". It is off by default.
+// Loggers derived via Prefix inherit the setting.
+func (l *DefaultLogger) SetDedupPrefix(enabled bool) {
+	l.uniqPrefix = enabled
+}
+
+// SetErrorHandler makes l call handler when writing a line fails,
+// instead of panicking, which is the default. The handler is called
+// without l's lock held, so it may itself log, say to a fallback
+// logger. Loggers derived via Prefix inherit the handler.
+func (l *DefaultLogger) SetErrorHandler(handler func(error)) {
+	l.onError = handler
+}
+
+// A Hook is called with the level, prefix and message of each line a
+// logger writes, such as to count warnings for a metrics system.
+type Hook func(level Level, prefix, msg string)
+
+// AddHook registers h to be called synchronously after each line l
+// writes, which for Panic and Fatal is before panicking or exiting.
+// Loggers derived from l via Prefix afterwards inherit the hook.
+func (l *DefaultLogger) AddHook(h Hook) {
+	l.hooks = append(l.hooks[:len(l.hooks):len(l.hooks)], h)
+}
+
+// SetLineTerminator sets the string written after each line, which
+// is "\n" by default. It may be "\r\n" for Windows consumers, or
+// even empty for writers that frame records themselves. It applies
+// to every output format. Loggers derived via Prefix inherit it.
+func (l *DefaultLogger) SetLineTerminator(eol string) {
+	l.eol = eol
+	l.eolSet = true
+}
+
+// terminator returns the string to write after each line.
+func (l *DefaultLogger) terminator() string {
+	if !l.eolSet {
+		return "\n"
+	}
+	return l.eol
+}
+
+// SetTimeFormat sets the layout used for timestamps, as understood
+// by time.Time.Format. The default is time.RFC3339; something like
+// time.RFC3339Nano is handy for performance debugging. Loggers
+// derived via Prefix inherit the layout.
+func (l *DefaultLogger) SetTimeFormat(layout string) {
+	l.timeFormat = layout
+}
+
+// SetSeverityPrefix makes l start each line with the level's syslog
+// severity, as returned by Level.Severity, so that downstream tools
+// can sort and filter numerically. In text and CSV output it is the
+// first column, and in JSON and logfmt output the first key,
+// severity. It is off by default. Loggers derived via Prefix inherit
+// the setting.
+func (l *DefaultLogger) SetSeverityPrefix(enabled bool) {
+	l.severity = enabled
+}
+
+// A header is the header line shared by a tree of loggers, and
+// whether it has been written to the current file. It is guarded by
+// the loggers' lock.
+type header struct {
+	fn      func() string
+	written bool
+}
+
+// SetHeader makes l write the line returned by fn before its first
+// line, such as to describe the format and start time of a log file,
+// or to name the columns of CSV output. It is written once, however
+// many goroutines race to write first, and again whenever Reopen or
+// a RotatingWriter starts a fresh file, but not to a file made by
+// NewFile or NewRotatingWriter that already has lines in it, since
+// they are appended after its header. For a logger made by NewSplit,
+// it is only written to the info writer. Loggers derived from l
+// afterwards share the header, so it isn't repeated for them. A nil
+// fn removes it, from the files a RotatingWriter rotates to as well.
+func (l *DefaultLogger) SetHeader(fn func() string) {
+	if fn == nil {
+		l.header = nil
+		if r := l.rotatingWriter(); r != nil {
+			r.SetRotateHeader(nil)
+		}
+		return
+	}
+	l.header = &header{fn: fn}
+}
+
+// rotatingWriter returns the RotatingWriter l writes to, beneath its
+// buffer if it has one, or nil if it doesn't write to one.
+func (l *DefaultLogger) rotatingWriter() *RotatingWriter {
+	w := l.writer()
+	if b := l.buffer(); b != nil {
+		w = b.under
+	}
+	r, _ := w.(*RotatingWriter)
+	return r
+}
+
+// A dataFile is a file writer that can tell whether the file it is
+// appending to already has something in it.
+type dataFile interface {
+	hasData() bool
+}
+
+// writeHeader writes the header line if it hasn't been written yet.
+// The caller must hold l's lock.
+func (l *DefaultLogger) writeHeader() error {
+	h := l.header
+	if h == nil || h.written {
+		return nil
+	}
+	h.written = true
+	term := l.terminator()
+	w := l.writer()
+	if b := l.buffer(); b != nil {
+		w = b.under
+	}
+	if r, ok := w.(*RotatingWriter); ok {
+		// the writer starts each file it rotates to, since the
+		// logger can't tell when it does
+		r.SetRotateHeader(func() []byte { return []byte(h.fn() + term) })
+	}
+	if f, ok := w.(dataFile); ok && f.hasData() {
+		return nil
+	}
+	_, err := io.WriteString(l.writer(), h.fn()+term)
+	return err
+}
+
+// SetOmitEmptyPrefix makes l leave the prefix column, and the tab
+// after it, out of text lines with an empty prefix, rather than
+// writing an empty column, which some parsers take for two tabs in
+// a row. It is off by default, since it means lines with and without
+// a prefix have different numbers of columns: a parser counting them
+// should only see the lines of loggers with no prefix at all, or
+// treat a line one column short as having an empty prefix. The
+// other formats always have a prefix. Loggers derived via Prefix
+// inherit the setting.
+func (l *DefaultLogger) SetOmitEmptyPrefix(enabled bool) {
+	l.omitPrefix = enabled
+}
+
+// SetSingleLine makes l escape line breaks in messages as \n and
+// \r, so that every line written is exactly one record even when a
+// message spans several lines. In text output tabs are escaped as
+// \t too, so that they can't shift the columns, and so is the stack
+// written by SetPanicStack. JSON and logfmt output escapes all of
+// these already. It is off by default for compatibility, but
+// recommended wherever lines are parsed. Loggers derived via Prefix
+// inherit the setting.
+func (l *DefaultLogger) SetSingleLine(enabled bool) {
+	l.singleLine = enabled
+}
+
+// The replacers for SetSingleLine.
+var (
+	newlineEscaper = strings.NewReplacer("\n", `\n`, "\r", `\r`)
+	textEscaper    = strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`)
+)
+
+// SetCrashWriter makes Panic also write its line, with the stack of
+// the calling goroutine, to w, such as a dedicated crash log file, so
+// that there is one place to look after a crash. The crash line is a
+// text line whatever l's format, and is written first, whatever the
+// threshold, so that it gets out even if the main writer turns out
+// to be broken; errors writing it are ignored. Loggers derived via
+// Prefix inherit the writer.
+func (l *DefaultLogger) SetCrashWriter(w io.Writer) {
+	l.crashW = w
+}
+
+// crash writes the Panic line e to the crash writer, if there is
+// one. It takes the entry the main writer gets, rather than making
+// its own, so that the line takes one sequence number, not two.
+func (l *DefaultLogger) crash(e entry) {
+	if l.crashW == nil {
+		return
+	}
+	e.fields = l.redacted(l.timeFields(e.fields))
+	e.stack = stackOutside()
+	line := append(textLine(e, false, nil), l.terminator()...)
+	l.crashW.Write(line)
+	if s, ok := l.crashW.(syncer); ok {
+		s.Sync()
+	}
+}
+
+// SetTimestamp enables or disables the timestamp, which is on by
+// default. Turn it off when something downstream, such as systemd or
+// Docker, timestamps lines already: text lines then start at the
+// level column, and the other formats leave out the time key or
+// column. Loggers derived via Prefix inherit the setting.
+func (l *DefaultLogger) SetTimestamp(enabled bool) {
+	l.noTime = !enabled
+}
+
+// SetRelativeTime makes l write the time elapsed since now in place
+// of the time of day, as in "[  0.123s]", which reads better in the
+// output of a short-lived process such as a CLI tool or a test. Now
+// is read from l's clock, so set any Clock first. Loggers derived
+// via Prefix afterwards measure from the same start. Disabling it
+// goes back to the time of day. A Formatter is still given the time
+// of day, to format as it likes.
+func (l *DefaultLogger) SetRelativeTime(enabled bool) {
+	if !enabled {
+		l.start = time.Time{}
+	} else if l.start.IsZero() {
+		l.start = l.now()
+	}
+}
+
+// elapsed formats d for SetRelativeTime, in seconds to the
+// millisecond.
+func elapsed(d time.Duration) string {
+	return fmt.Sprintf("[%7.3fs]", d.Seconds())
+}
+
+// SetLocalTime makes timestamps use the local time zone instead of
+// UTC, which is the default.
+func (l *DefaultLogger) SetLocalTime(local bool) {
+	l.localTime = local
+}
+
+// SetClock replaces the source of timestamps, which is normally the
+// system clock. Loggers derived via Prefix share the clock.
+func (l *DefaultLogger) SetClock(c Clock) {
+	l.clock = c
+}
+
+// now returns the current time from the logger's clock.
+func (l *DefaultLogger) now() time.Time {
+	if l.clock == nil {
+		return time.Now()
+	}
+	return l.clock.Now()
+}
+
+// formatTime formats t per the logger's settings.
+func (l *DefaultLogger) formatTime(t time.Time) string {
+	if !l.localTime {
+		t = t.UTC()
+	}
+	layout := l.timeFormat
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	return t.Format(layout)
+}
+
+// SetMaxMessageLength limits messages to n runes, cutting longer
+// ones short and marking them with "…(truncated)", so that an
+// accidentally huge payload doesn't bloat the log. Fields aren't
+// counted. Zero, the default, means no limit. Loggers derived via
+// Prefix inherit the limit.
+func (l *DefaultLogger) SetMaxMessageLength(n int) {
+	l.maxMsg = n
+}
+
+// truncate cuts t to the maximum message length, if it exceeds it.
+func (l *DefaultLogger) truncate(t string) string {
+	if l.maxMsg <= 0 || utf8.RuneCountInString(t) <= l.maxMsg {
+		return t
+	}
+	n := 0
+	for i := range t {
+		if n == l.maxMsg {
+			return t[:i] + "…(truncated)"
+		}
+		n++
+	}
+	return t
+}
+
+// SetPanicStack makes Panic write the stack of the goroutine that
+// called it, starting at the caller rather than inside this package.
+// In text output the stack is a last column, spanning several lines,
+// and in JSON and logfmt output it's the stack key. It is off by
+// default since capturing the stack is slow and the output noisy.
+// Loggers derived via Prefix inherit the setting.
+func (l *DefaultLogger) SetPanicStack(enabled bool) {
+	l.panicStack = enabled
+}
+
+// SetPanicValue makes Panic panic with the value f returns for the
+// message, rather than with the message itself, so that a deferred
+// recover can type-switch on it. NewPanicError makes a *PanicError:
+//
+//	l.SetPanicValue(NewPanicError)
+//
+// A nil f restores the default. Loggers derived via Prefix inherit
+// the setting.
+func (l *DefaultLogger) SetPanicValue(f func(msg string) interface{}) {
+	l.panicValue = f
+}
+
+// A PanicError is a panic value for SetPanicValue, carrying the
+// message and when the panic happened.
+type PanicError struct {
+	Message string
+	Time    time.Time
+}
+
+// NewPanicError returns a *PanicError for msg, timestamped now.
+func NewPanicError(msg string) interface{} {
+	return &PanicError{Message: msg, Time: time.Now()}
+}
+
+// Error returns the message.
+func (e *PanicError) Error() string {
+	return e.Message
+}
+
+// SetErrorDetail makes Error, Panic and Fatal format any error
+// argument that implements fmt.Formatter, as the errors of packages
+// like github.com/pkg/errors do, with %+v rather than the verb in
+// the format, so that the line carries its details, such as a stack
+// trace. An error that doesn't is searched with errors.Unwrap for one
+// that does, or that has a StackTrace method, whose details then
+// follow the error's own message. It is off by default to keep lines
+// short. Loggers derived via Prefix inherit the setting.
+func (l *DefaultLogger) SetErrorDetail(enabled bool) {
+	l.errDetail = enabled
+}
+
+// sprintf formats a message at level, expanding error arguments if
+// SetErrorDetail is on.
+func (l *DefaultLogger) sprintf(level Level, format string, v []interface{}) string {
+	if !l.errDetail || level < ErrorLevel {
+		return fmt.Sprintf(format, v...)
+	}
+	args := v
+	copied := false
+	for i, a := range v {
+		err, ok := a.(error)
+		if !ok {
+			continue
+		}
+		d, ok := errorDetail(err)
+		if !ok {
+			continue
+		}
+		if !copied {
+			// the caller's slice isn't ours to change
+			args = append([]interface{}(nil), v...)
+			copied = true
+		}
+		args[i] = d
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// errorDetail returns err with the details of the first error in its
+// chain that implements fmt.Formatter or has a StackTrace method, and
+// false if none does.
+func errorDetail(err error) (string, bool) {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if _, ok := e.(fmt.Formatter); ok {
+			d := fmt.Sprintf("%+v", e)
+			if e == err {
+				return d, true
+			}
+			// the %+v of github.com/pkg/errors starts with the
+			// message, which err's already includes
+			if strings.HasPrefix(d, e.Error()) {
+				return err.Error() + d[len(e.Error()):], true
+			}
+			return err.Error() + "\n" + d, true
+		}
+		if st, ok := stackTrace(e); ok {
+			return err.Error() + st, true
+		}
+	}
+	return "", false
+}
+
+// stackTrace returns the %+v of what e's StackTrace method returns,
+// if it has one. It is looked up by name, since the result type, such
+// as that of github.com/pkg/errors, can't be named here.
+func stackTrace(e error) (string, bool) {
+	m := reflect.ValueOf(e).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return "", false
 	}
+	return fmt.Sprintf("%+v", m.Call(nil)[0].Interface()), true
 }
 
-// Info writes to os.Stderr, but does not record anything more or
-// less important than other logging levels.
+// SetCaller makes l write the file:line of the code that called the
+// logging method, as a column just before the message. It is off by
+// default since looking up the caller is relatively slow.
+func (l *DefaultLogger) SetCaller(enabled bool) {
+	l.caller = enabled
+}
+
+// Enabled reports whether l would write a line at level, so that
+// callers can skip building expensive arguments when it wouldn't.
+func (l *DefaultLogger) Enabled(level Level) bool {
+	return l.enabled(level)
+}
+
+// enabled reports whether lines at level pass the threshold, and
+// would go anywhere. Lines for io.Discard are dropped before
+// formatting, unless a hook wants to see them, so that pointing a
+// logger at io.Discard to turn it off costs next to nothing. Panic
+// and Fatal still panic and exit.
+func (l *DefaultLogger) enabled(level Level) bool {
+	if level < l.level.get() {
+		return false
+	}
+	return len(l.hooks) > 0 || l.writerFor(level) != io.Discard
+}
+
+// writer returns the configured writer, falling back to stderr for
+// a zero DefaultLogger.
+func (l *DefaultLogger) writer() io.Writer {
+	if w := l.w.get(); w != nil {
+		return w
+	}
+	return os.Stderr
+}
+
+// SetLevelWriter routes lines at exactly level to w, such as to send
+// errors to a file of their own while everything else goes to the
+// main writer. A nil w routes the level back to the main writer, or
+// for a logger made by NewSplit to whichever of its writers the
+// level went to. Loggers derived via Prefix afterwards inherit the
+// routing, and Close closes the level writers too.
+func (l *DefaultLogger) SetLevelWriter(level Level, w io.Writer) {
+	levelW := make(map[Level]io.Writer, len(l.levelW)+1)
+	for lv, lw := range l.levelW {
+		levelW[lv] = lw
+	}
+	if w == nil {
+		delete(levelW, level)
+	} else {
+		levelW[level] = w
+	}
+	l.levelW = levelW
+}
+
+// writerFor returns the writer for lines at level, which is the one
+// set by SetLevelWriter if there is one, and otherwise the error
+// writer for Warning and above if l was made by NewSplit.
+func (l *DefaultLogger) writerFor(level Level) io.Writer {
+	if w, ok := l.levelW[level]; ok {
+		return w
+	}
+	if l.errW != nil && level >= WarningLevel {
+		return l.errW
+	}
+	return l.writer()
+}
+
+// writers returns w along with each other distinct writer l sends
+// lines to.
+func (l *DefaultLogger) writers(w io.Writer) []io.Writer {
+	ws := []io.Writer{w}
+	add := func(w io.Writer) {
+		for _, seen := range ws {
+			if seen == w {
+				return
+			}
+		}
+		ws = append(ws, w)
+	}
+	if l.errW != nil {
+		add(l.errW)
+	}
+	for _, lw := range l.levelW {
+		add(lw)
+	}
+	return ws
+}
+
+// An entry is everything known about a single line before it is
+// formatted.
+type entry struct {
+	at     time.Time
+	time   string
+	level  Level
+	prefix string
+	caller string
+	msg    string
+	fields []field
+	stack  string
+
+	// severity is set to write the level's syslog severity first
+	severity bool
+
+	// omitPrefix is set to leave out the prefix column of text
+	// output when the prefix is empty
+	omitPrefix bool
+}
+
+// mutex returns the lock guarding l's writer.
+func (l *DefaultLogger) mutex() *sync.Mutex {
+	if l.mu == nil {
+		return &zeroMu
+	}
+	return l.mu
+}
+
+// out writes a line, and then handles any error per l's error
+// policy: calling the handler from SetErrorHandler, or panicking.
+func (l *DefaultLogger) out(level Level, t string) {
+	l.outEntry(l.entry(level, t))
+}
+
+// outEntry is like out, but writes an entry built by the caller.
+func (l *DefaultLogger) outEntry(e entry) {
+	if err := l.write(e); err != nil {
+		if l.onError != nil {
+			l.onError(err)
+			return
+		}
+		panic(fmt.Sprintf("Failed to write log!\nError: %v\nLog: %s\n", err, e.msg))
+	}
+}
+
+// entry collects what goes into a line at level with message t.
+func (l *DefaultLogger) entry(level Level, t string) entry {
+	e := entry{
+		level:    level,
+		prefix:   l.prefix,
+		msg:      l.truncate(t),
+		fields:   l.fields,
+		severity: l.severity,
+
+		omitPrefix: l.omitPrefix,
+	}
+	if !l.noTime {
+		e.at = l.now()
+		if l.start.IsZero() {
+			e.time = l.formatTime(e.at)
+		} else {
+			e.time = elapsed(e.at.Sub(l.start))
+		}
+	}
+	if l.caller {
+		e.caller = callerOutside()
+	}
+	if l.seq != nil || l.pid || l.hostname != "" || l.corrID != "" {
+		e.fields = l.processFields()
+	}
+	return e
+}
+
+// write formats and writes e, returning any error from the writer.
+func (l *DefaultLogger) write(e entry) error {
+	mu := l.mutex()
+	e.fields = l.redacted(l.timeFields(e.fields))
+	if l.singleLine && l.formatter == nil {
+		switch l.format {
+		case formatText:
+			e.msg = textEscaper.Replace(e.msg)
+			e.stack = textEscaper.Replace(e.stack)
+		case formatCSV:
+			e.msg = newlineEscaper.Replace(e.msg)
+			e.stack = newlineEscaper.Replace(e.stack)
+		}
+	}
+
+	var line []byte
+	switch {
+	case l.formatter != nil:
+		var err error
+		line, err = l.formatter.Format(e.level, e.at, e.prefix, e.msg, exportFields(e.fields))
+		if err != nil {
+			return err
+		}
+	case l.format == formatJSON:
+		line = jsonLine(e)
+	case l.format == formatLogfmt:
+		line = logfmtLine(e)
+	case l.format == formatCSV:
+		line = csvLine(e)
+	default:
+		line = textLine(e, l.color, l.columns)
+	}
+	// the formatters leave the line terminator to us
+	line = append(line, l.terminator()...)
+
+	mu.Lock()
+	err := l.writeHeader()
+	if err == nil {
+		_, err = l.writerFor(e.level).Write(line)
+	}
+	if err == nil && e.level >= l.flushLevel {
+		if b := l.buffer(); b != nil {
+			err = b.Flush()
+		}
+	}
+	mu.Unlock()
+
+	if l.counts != nil {
+		l.counts.count(e.level, err == nil)
+	}
+	for _, h := range l.hooks {
+		h(e.level, l.prefix, e.msg)
+	}
+	return err
+}
+
+// prefixColumn returns the prefix column of a text line, with the
+// tab that ends it, or "" if it is to be left out.
+func (e entry) prefixColumn() string {
+	if e.omitPrefix && e.prefix == "" {
+		return ""
+	}
+	return e.prefix + "\t"
+}
+
+// textLine renders e as tab-separated columns, in the default
+// layout unless columns are given, with the level column wrapped in
+// ANSI color codes if color is set.
+func textLine(e entry, color bool, columns []Column) []byte {
+	var line string
+	switch {
+	case len(columns) > 0:
+		line = columnLine(e, color, columns)
+	case e.caller != "":
+		line = fmt.Sprintf("%s\t%s%s\t%s%s",
+			levelTag(e.level, color), e.prefixColumn(), e.caller, e.msg, textFields(e.fields))
+	default:
+		line = fmt.Sprintf("%s\t%s%s%s",
+			levelTag(e.level, color), e.prefixColumn(), e.msg, textFields(e.fields))
+	}
+	if e.time != "" && len(columns) == 0 {
+		line = e.time + "\t" + line
+	}
+	if e.severity {
+		line = strconv.Itoa(e.level.Severity()) + "\t" + line
+	}
+	if e.stack != "" {
+		line += "\t" + e.stack
+	}
+	return []byte(line)
+}
+
+// Log writes to the logger's output at level, which lets the level
+// be chosen at run time. Lines below the threshold are dropped
+// without formatting their arguments. At PanicLevel it panics just
+// like Panic, and at FatalLevel or above it exits just like Fatal.
+func (l *DefaultLogger) Log(level Level, format string, v ...interface{}) {
+	switch {
+	case level >= FatalLevel:
+		l.fatal(l.sprintf(level, format, v))
+		osExit(1)
+	case level == PanicLevel:
+		t := l.sprintf(level, format, v)
+		enabled := l.enabled(PanicLevel)
+		if enabled || l.crashW != nil {
+			e := l.entry(PanicLevel, t)
+			l.crash(e)
+			if enabled {
+				if l.panicStack {
+					e.stack = stackOutside()
+				}
+				l.outEntry(e)
+				l.sync()
+			}
+		}
+		if l.panicValue != nil {
+			panic(l.panicValue(t))
+		}
+		panic(t)
+	case l.enabled(level):
+		l.out(level, l.sprintf(level, format, v))
+	}
+}
+
+// LogErr is like Log below PanicLevel, but returns the error from
+// the writer instead of applying l's error policy, so that the
+// caller can fall back to another sink. A line below the threshold
+// isn't written, and returns nil. At PanicLevel and above it behaves
+// just like Log, and so doesn't return.
+func (l *DefaultLogger) LogErr(level Level, format string, v ...interface{}) error {
+	if level >= PanicLevel {
+		l.Log(level, format, v...)
+		return nil
+	}
+	if !l.enabled(level) {
+		return nil
+	}
+	return l.write(l.entry(level, l.sprintf(level, format, v)))
+}
+
+// DebugErr is like Debug, but returns the error from the writer.
+func (l *DefaultLogger) DebugErr(format string, v ...interface{}) error {
+	return l.LogErr(DebugLevel, format, v...)
+}
+
+// InfoErr is like Info, but returns the error from the writer.
+func (l *DefaultLogger) InfoErr(format string, v ...interface{}) error {
+	return l.LogErr(InfoLevel, format, v...)
+}
+
+// WarningErr is like Warning, but returns the error from the writer.
+func (l *DefaultLogger) WarningErr(format string, v ...interface{}) error {
+	return l.LogErr(WarningLevel, format, v...)
+}
+
+// ErrorErr is like Error, but returns the error from the writer.
+func (l *DefaultLogger) ErrorErr(format string, v ...interface{}) error {
+	return l.LogErr(ErrorLevel, format, v...)
+}
+
+// Debug writes to the logger's output, tagged DEBUG, if the
+// threshold has been lowered with SetDebug or SetLevel. Otherwise it
+// returns without formatting anything.
+func (l *DefaultLogger) Debug(format string, v ...interface{}) {
+	l.Log(DebugLevel, format, v...)
+}
+
+// Info writes to the logger's output, tagged INFO.
 func (l *DefaultLogger) Info(format string, v ...interface{}) {
-	l.out(fmt.Sprintf(format, v...))
+	l.Log(InfoLevel, format, v...)
 }
 
-// Warning writes to os.Stderr, but does not record anything more or
-// less important than other logging levels.
+// Warning writes to the logger's output, tagged WARN.
 func (l *DefaultLogger) Warning(format string, v ...interface{}) {
-	l.out(fmt.Sprintf(format, v...))
+	l.Log(WarningLevel, format, v...)
 }
 
-// Panic writes to os.Stderr, and then panics. It doesn't record
-// anything more or less important than other logging levels. But
-// it does panic, so steady now.
+// Error writes to the logger's output, tagged ERROR, for serious
+// conditions that the program can nonetheless recover from.
+func (l *DefaultLogger) Error(format string, v ...interface{}) {
+	l.Log(ErrorLevel, format, v...)
+}
+
+// Panic writes to the logger's output, tagged PANIC, and then
+// panics. It does panic, so steady now, even if the threshold
+// suppresses the line.
 func (l *DefaultLogger) Panic(format string, v ...interface{}) {
-	t := fmt.Sprintf(format, v...)
-	l.out(t)
-	// if stderr is redirected, let's flush to storage in case we
-	// are about to reboot or crash
-	os.Stderr.Sync()
-	panic(t)
+	l.Log(PanicLevel, format, v...)
+}
+
+// Fatal writes to the logger's output, tagged FATAL, and then exits
+// the process with status 1, without the stack dump of a panic.
+func (l *DefaultLogger) Fatal(format string, v ...interface{}) {
+	l.Log(FatalLevel, format, v...)
+}
+
+// fatal writes the line for Fatal, without exiting, so that Multi
+// can write it to every logger before exiting itself.
+func (l *DefaultLogger) fatal(t string) {
+	if l.enabled(FatalLevel) {
+		l.out(FatalLevel, t)
+		l.sync()
+	}
+}
+
+// sync flushes any buffered lines, and then flushes the output to
+// storage if it's a file, in case we are about to reboot or crash.
+// It is best-effort: it syncs whichever writers l is configured
+// with that support it, and ignores their errors, since many can't
+// sync at all (a pipe or terminal on stderr, say) and the line has
+// already been written by then. A failed sync is never reported as
+// a write error, and never changes what Panic panics with.
+func (l *DefaultLogger) sync() {
+	w := l.writer()
+	if b := l.buffer(); b != nil {
+		l.Flush()
+		w = b.under
+	}
+	for _, w := range l.writers(w) {
+		if s, ok := w.(syncer); ok {
+			s.Sync()
+		}
+	}
 }
 
 // Must will call l.Panic() if err is not nil, otherwise will
@@ -69,29 +1100,115 @@ func (l *DefaultLogger) Panic(format string, v ...interface{}) {
 func (l *DefaultLogger) Must(message string, err error) {
 	if err == nil {
 		if l.Trace {
-			l.Info("ok: %s")
+			l.Info("ok: %s", message)
 		}
 		return
 	}
 	l.Panic("Failed to %s: %v", message, err)
 }
 
-// Prefix returns a new DefaultLogger with this prefix appended.
+// Clone returns an independent copy of l, with the same prefix,
+// fields and settings, that can be reconfigured without affecting
+// l. Unlike loggers derived via Prefix, the clone has a threshold
+// and output setting of its own. It starts out writing to l's
+// current writer, which l keeps ownership of, so closing the clone
+// doesn't close the writer. The clone of a buffered logger writes
+// into l's buffer, and flushes it as l would, but SetOutput on the
+// clone leaves the buffer to l and makes the clone unbuffered.
+func (l *DefaultLogger) Clone() *DefaultLogger {
+	nl := *l
+	nl.level = &levelVar{}
+	nl.level.set(l.level.get())
+	nl.w = newWriterVar(l.writer())
+	nl.buf = nil
+	return &nl
+}
+
+// Tee returns a copy of l that also writes each line to w, so that
+// its output can be mirrored, say into memory for a debug endpoint,
+// with all of l's formatting settings. It shares l's threshold and
+// lock like a logger derived via Prefix, and so doesn't own w:
+// closing it closes neither w nor l's writer.
+func (l *DefaultLogger) Tee(w io.Writer) *DefaultLogger {
+	nl := *l
+	nl.w = newWriterVar(io.MultiWriter(l.writer(), w))
+	if l.errW != nil {
+		nl.errW = io.MultiWriter(l.errW, w)
+	}
+	if l.levelW != nil {
+		// levels sharing a writer share its mirror, so that it's
+		// still one writer to writers
+		mirrors := make(map[io.Writer]io.Writer, len(l.levelW))
+		nl.levelW = make(map[Level]io.Writer, len(l.levelW))
+		for lv, lw := range l.levelW {
+			if mirrors[lw] == nil {
+				mirrors[lw] = io.MultiWriter(lw, w)
+			}
+			nl.levelW[lv] = mirrors[lw]
+		}
+	}
+	return &nl
+}
+
+// CurrentPrefix returns the prefix accumulated by Prefix calls so
+// far, as it appears in the prefix column.
+func (l *DefaultLogger) CurrentPrefix() string {
+	return l.prefix
+}
+
+// Prefix returns a new DefaultLogger with this prefix appended,
+// sharing all other settings with l.
 func (l *DefaultLogger) Prefix(prefix string) Logger {
-	nl := &DefaultLogger{Trace: l.Trace}
+	return l.PrefixAll(prefix)
+}
 
-	if l.prefix == "" {
-		nl.prefix = prefix
-	} else {
-		nl.prefix = fmt.Sprintf("%s:%s", l.prefix, prefix)
+// PrefixAll is like chaining Prefix once per part, but makes only
+// the one derived logger, for hot paths that build a prefix from
+// several parts.
+func (l *DefaultLogger) PrefixAll(parts ...string) Logger {
+	nl := *l
+
+	sep := l.sep
+	if sep == "" {
+		sep = ":"
+	}
+
+	var b strings.Builder
+	b.WriteString(l.prefix)
+	last := l.prefix
+	if i := strings.LastIndex(last, sep); i >= 0 {
+		last = last[i+len(sep):]
+	}
+	for _, part := range parts {
+		if l.uniqPrefix && b.Len() > 0 && part == last {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(part)
+		last = part
 	}
+	nl.prefix = b.String()
 
-	return nl
+	return &nl
 }
 
-// A NullLogger discards all Info and Warning logs, and simply
-// panics all Panic logs.
-type NullLogger struct{}
+// A NullLogger discards all Debug, Info, Warning and Error logs,
+// simply panics all Panic logs, and exits on all Fatal logs. A
+// NullLogger from SilentNull discards those too.
+//
+// The discarding methods do nothing, but the call still boxes its
+// arguments. Code that may be handed a NullLogger can skip that,
+// and building the arguments, by guarding the call with Enabled,
+// which costs no allocation:
+//
+//	if l.Enabled(DebugLevel) {
+//		l.Debug("request: %s", dump(req))
+//	}
+type NullLogger struct {
+	silent bool
+}
 
 // Null returns a fully-initialized, ready-to-use, standards
 // compliant, community endorsed, efficient, scalable, redundant,
@@ -101,17 +1218,89 @@ func Null() *NullLogger {
 	return &NullLogger{}
 }
 
+// OrNull returns l, or a NullLogger if l is nil, including a nil
+// pointer of some Logger type. It lets code holding an optional
+// Logger, such as a struct field nobody set, call methods safely:
+//
+//	s.log = OrNull(s.log)
+func OrNull(l Logger) Logger {
+	if l == nil {
+		return Null()
+	}
+	if v := reflect.ValueOf(l); v.Kind() == reflect.Ptr && v.IsNil() {
+		return Null()
+	}
+	return l
+}
+
+// Must returns l if err is nil, and otherwise panics with err, so
+// that a constructor that can fail can be used to initialize a
+// package-level variable:
+//
+//	var logger = log.Must(log.NewFile("/var/log/app.log"))
+func Must(l Logger, err error) Logger {
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// SilentNull returns a NullLogger that discards everything, even
+// Panic and Fatal, which return normally instead of panicking or
+// exiting. It suits tests of error paths that call Panic, where the
+// test has other things to check.
+func SilentNull() *NullLogger {
+	return &NullLogger{silent: true}
+}
+
+// Debug discards the logged data.
+func (n *NullLogger) Debug(format string, v ...interface{}) {}
+
 // Info discards the logged data.
 func (n *NullLogger) Info(format string, v ...interface{}) {}
 
 // Warning discards the logged data.
 func (n *NullLogger) Warning(format string, v ...interface{}) {}
 
-// Panic formats the data into a string, then panics.
+// Error discards the logged data.
+func (n *NullLogger) Error(format string, v ...interface{}) {}
+
+// Panic formats the data into a string, then panics, unless n is
+// silent.
 func (n *NullLogger) Panic(format string, v ...interface{}) {
+	if n.silent {
+		return
+	}
 	panic(fmt.Sprintf(format, v...))
 }
 
+// Fatal discards the logged data, then exits the process with
+// status 1, unless n is silent.
+func (n *NullLogger) Fatal(format string, v ...interface{}) {
+	if n.silent {
+		return
+	}
+	osExit(1)
+}
+
+// Log discards the logged data, except that it panics at PanicLevel
+// and exits at FatalLevel like Panic and Fatal.
+func (n *NullLogger) Log(level Level, format string, v ...interface{}) {
+	switch {
+	case level >= FatalLevel:
+		n.Fatal(format, v...)
+	case level == PanicLevel:
+		n.Panic(format, v...)
+	}
+}
+
+// Enabled reports false, since n discards everything, except that
+// PanicLevel and above are enabled unless n is silent, since they
+// still panic or exit.
+func (n *NullLogger) Enabled(level Level) bool {
+	return level >= PanicLevel && !n.silent
+}
+
 // Must calls n.Panic() if err is not nil.
 func (n *NullLogger) Must(message string, err error) {
 	if err != nil {
@@ -124,3 +1313,15 @@ func (n *NullLogger) Must(message string, err error) {
 func (n *NullLogger) Prefix(prefix string) Logger {
 	return n
 }
+
+// With returns a pointer to the NullLogger and discards the
+// provided field.
+func (n *NullLogger) With(key string, value interface{}) Logger {
+	return n
+}
+
+// WithError returns a pointer to the NullLogger and discards the
+// provided error.
+func (n *NullLogger) WithError(err error) Logger {
+	return n
+}
@@ -0,0 +1,1664 @@
+package log
+
+import "fmt"
+import "errors"
+import "sync"
+import "regexp"
+import "testing"
+import "time"
+import "runtime"
+import "strings"
+import "os"
+import "io"
+import "path/filepath"
+
+// testTime is the time of every line written by a logger from
+// newLogger.
+var testTime = time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+
+// newLogger returns a logger writing to a new buffer, with its clock
+// stopped at testTime so that lines can be compared exactly.
+func newLogger() (*DefaultLogger, *CaptureBuffer) {
+	l, buf := NewBuffer()
+	l.SetClock(FixedClock(testTime))
+	return l, buf
+}
+
+// catchPanic calls f, and returns the value it panicked with, or nil
+// if it returned normally.
+func catchPanic(f func()) (v interface{}) {
+	defer func() { v = recover() }()
+	f()
+	return nil
+}
+
+// stubExit stops osExit from ending the test binary until the test
+// is over, and returns where the status it was last called with is
+// kept, which is -1 until it is called.
+func stubExit(t *testing.T) *int {
+	t.Helper()
+	code := -1
+	old := osExit
+	osExit = func(c int) { code = c }
+	t.Cleanup(func() { osExit = old })
+	return &code
+}
+
+func TestLevelTags(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l *DefaultLogger)
+		want string
+	}{
+		{"Trace", func(l *DefaultLogger) { l.Log(TraceLevel, "hello") }, "TRACE"},
+		{"Debug", func(l *DefaultLogger) { l.Debug("hello") }, "DEBUG"},
+		{"Info", func(l *DefaultLogger) { l.Info("hello") }, "INFO"},
+		{"Warning", func(l *DefaultLogger) { l.Warning("hello") }, "WARN"},
+		{"Error", func(l *DefaultLogger) { l.Error("hello") }, "ERROR"},
+		{"Panic", func(l *DefaultLogger) { catchPanic(func() { l.Panic("hello") }) }, "PANIC"},
+		{"Fatal", func(l *DefaultLogger) { l.Fatal("hello") }, "FATAL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubExit(t)
+			l, buf := newLogger()
+			l.SetLevel(TraceLevel)
+			tt.log(l)
+			want := "2006-01-02T15:04:05Z\t" + tt.want + "\t\thello\n"
+			if got := buf.String(); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// slowWriter appends each byte of a write separately, yielding in
+// between, so that writes not serialized by the logger interleave.
+type slowWriter struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	for _, c := range p {
+		w.mu.Lock()
+		w.buf = append(w.buf, c)
+		w.mu.Unlock()
+		runtime.Gosched()
+	}
+	return len(p), nil
+}
+
+func TestConcurrentLinesNotTorn(t *testing.T) {
+	const goroutines, lines = 8, 50
+	w := &slowWriter{}
+	l := NewWriter(w)
+	l.SetClock(FixedClock(testTime))
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		// half of them through a derived logger, which must share
+		// the lock
+		var gl Logger = l
+		if g%2 == 1 {
+			gl = l.Prefix(fmt.Sprint("g", g))
+		}
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				gl.Info("goroutine %d line %d", g, i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	valid := regexp.MustCompile(`^2006-01-02T15:04:05Z\tINFO\t(g\d+)?\tgoroutine \d+ line \d+$`)
+	got := strings.Split(strings.TrimSuffix(string(w.buf), "\n"), "\n")
+	if len(got) != goroutines*lines {
+		t.Fatalf("got %d lines, want %d", len(got), goroutines*lines)
+	}
+	for _, line := range got {
+		if !valid.MatchString(line) {
+			t.Fatalf("torn line %q", line)
+		}
+	}
+}
+
+func TestLevelThreshold(t *testing.T) {
+	levels := []Level{TraceLevel, DebugLevel, InfoLevel, WarningLevel, ErrorLevel}
+	for _, threshold := range append(levels, PanicLevel) {
+		t.Run(threshold.String(), func(t *testing.T) {
+			l, buf := newLogger()
+			l.SetLevel(threshold)
+			// a derived logger must apply the same threshold
+			pl := l.Prefix("p")
+			for _, level := range levels {
+				buf.Reset()
+				pl.Log(level, "hello")
+				if written := buf.String() != ""; written != (level >= threshold) {
+					t.Errorf("%v written = %v at threshold %v", level, written, threshold)
+				}
+			}
+		})
+	}
+}
+
+func TestPanicIgnoresThreshold(t *testing.T) {
+	l, buf := newLogger()
+	l.SetLevel(FatalLevel)
+	if v := catchPanic(func() { l.Panic("boom %d", 1) }); v != "boom 1" {
+		t.Errorf("panicked with %v, want boom 1", v)
+	}
+	if buf.String() != "" {
+		t.Errorf("wrote %q above the threshold", buf.String())
+	}
+}
+
+func TestDisabledLevelSkipsFormatting(t *testing.T) {
+	l, _ := newLogger()
+	l.SetLevel(WarningLevel)
+	l.Info("%v", formatSpy(func() { t.Error("formatted the arguments of a disabled line") }))
+}
+
+// formatSpy calls itself when formatted.
+type formatSpy func()
+
+func (f formatSpy) String() string {
+	f()
+	return ""
+}
+
+func TestTimeFormat(t *testing.T) {
+	zone := time.FixedZone("test", 2*60*60)
+	tests := []struct {
+		name   string
+		layout string
+		local  bool
+		at     time.Time
+		want   string
+	}{
+		{"default", "", false, testTime, "2006-01-02T15:04:05Z"},
+		{"nano", time.RFC3339Nano, false, testTime.Add(123456 * time.Microsecond), "2006-01-02T15:04:05.123456Z"},
+		{"custom", "15:04:05.000", false, testTime.Add(7 * time.Millisecond), "15:04:05.007"},
+		{"utc", time.RFC3339, false, testTime.In(zone), "2006-01-02T15:04:05Z"},
+		{"local", time.RFC3339, true, testTime.In(zone), "2006-01-02T17:04:05+02:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := NewBuffer()
+			l.SetClock(FixedClock(tt.at))
+			l.SetTimeFormat(tt.layout)
+			l.SetLocalTime(tt.local)
+			// the layout must carry through Prefix
+			l.Prefix("p").Info("hello")
+			if got := strings.SplitN(buf.String(), "\t", 2)[0]; got != tt.want {
+				t.Errorf("got time %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixSeparator(t *testing.T) {
+	tests := []struct {
+		name string
+		sep  string
+		want string
+	}{
+		{"default", "", "host:8080:db:query"},
+		{"custom", " > ", "host:8080 > db > query"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			l.SetPrefixSeparator(tt.sep)
+			l.Prefix("host:8080").Prefix("db").Prefix("query").Info("hello")
+			want := "2006-01-02T15:04:05Z\tINFO\t" + tt.want + "\thello\n"
+			if got := buf.String(); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// errWriter fails every write with err.
+type errWriter struct {
+	err error
+}
+
+func (w errWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestErrorHandler(t *testing.T) {
+	errBroken := errors.New("broken pipe")
+	l := NewWriter(errWriter{errBroken})
+	var got []error
+	l.SetErrorHandler(func(err error) { got = append(got, err) })
+
+	l.Info("one")
+	// derived loggers inherit the handler
+	l.Prefix("p").Warning("two")
+	if len(got) != 2 || got[0] != errBroken || got[1] != errBroken {
+		t.Errorf("handler got %v, want errBroken twice", got)
+	}
+}
+
+func TestWriteErrorPanicsByDefault(t *testing.T) {
+	l := NewWriter(errWriter{errors.New("broken pipe")})
+	v := catchPanic(func() { l.Info("hello") })
+	if s, _ := v.(string); !strings.Contains(s, "broken pipe") {
+		t.Errorf("panicked with %v, want the write error", v)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	l := Named("http")
+	if p := l.CurrentPrefix(); p != "http" {
+		t.Errorf("got prefix %q, want http", p)
+	}
+
+	got, want := &CaptureBuffer{}, &CaptureBuffer{}
+	l.SetOutput(got)
+	l.SetClock(FixedClock(testTime))
+	d := Default()
+	d.SetOutput(want)
+	d.SetClock(FixedClock(testTime))
+
+	l.Prefix("access").Info("hello")
+	d.Prefix("http").Prefix("access").Info("hello")
+	if got.String() != want.String() {
+		t.Errorf("Named wrote %q, Default().Prefix wrote %q", got.String(), want.String())
+	}
+}
+
+// prefixTwice derives a logger through the Logger interface only,
+// as helpers taking a Logger do.
+func prefixTwice(l Logger) Logger {
+	l = l.Prefix("x")
+	l = l.Prefix("y")
+	return l
+}
+
+func TestPrefixThroughInterface(t *testing.T) {
+	t.Run("DefaultLogger", func(t *testing.T) {
+		d, buf := newLogger()
+		var l Logger = d
+		prefixTwice(l).Info("hello")
+		if want := "2006-01-02T15:04:05Z\tINFO\tx:y\thello\n"; buf.String() != want {
+			t.Errorf("got %q, want %q", buf.String(), want)
+		}
+		if _, ok := l.Prefix("x").(*DefaultLogger); !ok {
+			t.Errorf("Prefix returned %T, want *DefaultLogger", l.Prefix("x"))
+		}
+	})
+	t.Run("NullLogger", func(t *testing.T) {
+		var l Logger = Null()
+		if pl, ok := prefixTwice(l).(*NullLogger); !ok {
+			t.Errorf("Prefix returned %T, want *NullLogger", pl)
+		}
+	})
+
+	// each wrapper around a TestLogger must pass the prefix on to it
+	wrappers := []struct {
+		name string
+		wrap func(l Logger) (Logger, func())
+	}{
+		{"Async", func(l Logger) (Logger, func()) {
+			a := Async(l, 4, BlockWhenFull)
+			return a, func() { a.Close() }
+		}},
+		{"Dedup", func(l Logger) (Logger, func()) { return Dedup(l, 0), func() {} }},
+		{"Deferred", func(l Logger) (Logger, func()) { return Deferred(l, 4, TraceLevel), func() {} }},
+		{"FilterPrefix", func(l Logger) (Logger, func()) { return FilterPrefix(l, nil), func() {} }},
+		{"Heartbeat", func(l Logger) (Logger, func()) {
+			h := Heartbeat(l, time.Hour)
+			return h, func() { h.Close() }
+		}},
+		{"Multi", func(l Logger) (Logger, func()) { return Multi(l), func() {} }},
+		{"RateLimited", func(l Logger) (Logger, func()) {
+			r := RateLimited(l, 10, time.Second)
+			return r, func() { r.Close() }
+		}},
+		{"Ring", func(l Logger) (Logger, func()) { return Ring(4, l), func() {} }},
+		{"Sampled", func(l Logger) (Logger, func()) { return Sampled(l, 1), func() {} }},
+		{"TestLogger", func(l Logger) (Logger, func()) { return l, func() {} }},
+	}
+	for _, tt := range wrappers {
+		t.Run(tt.name, func(t *testing.T) {
+			tl := NewTest()
+			l, done := tt.wrap(tl)
+			prefixTwice(l).Info("hello")
+			done()
+			got := tl.Messages()
+			if len(got) != 1 || got[0].Prefix != "x:y" {
+				t.Errorf("got %+v, want one entry with prefix x:y", got)
+			}
+		})
+	}
+}
+
+// closeRecorder is a writer counting the calls to its Close.
+type closeRecorder struct {
+	CaptureBuffer
+	closed int
+}
+
+func (w *closeRecorder) Close() error {
+	w.closed++
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	tests := []struct {
+		name   string
+		close  func(l *DefaultLogger) error
+		closed int
+	}{
+		{"root", func(l *DefaultLogger) error { return l.Close() }, 1},
+		{"prefixed child", func(l *DefaultLogger) error { return l.Prefix("p").(*DefaultLogger).Close() }, 0},
+		{"child with fields", func(l *DefaultLogger) error { return l.With("k", 1).(*DefaultLogger).Close() }, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &closeRecorder{}
+			l := NewWriter(w)
+			if err := tt.close(l); err != nil {
+				t.Fatal(err)
+			}
+			if w.closed != tt.closed {
+				t.Errorf("closed %d times, want %d", w.closed, tt.closed)
+			}
+		})
+	}
+}
+
+func TestCloseSplit(t *testing.T) {
+	info, errs := &closeRecorder{}, &closeRecorder{}
+	NewSplit(info, errs).Close()
+	if info.closed != 1 || errs.closed != 1 {
+		t.Errorf("closed %d and %d times, want 1 each", info.closed, errs.closed)
+	}
+	// a writer used for both is closed once
+	both := &closeRecorder{}
+	NewSplit(both, both).Close()
+	if both.closed != 1 {
+		t.Errorf("closed %d times, want 1", both.closed)
+	}
+}
+
+func TestCloseFlushes(t *testing.T) {
+	w := &closeRecorder{}
+	l := NewBuffered(w, 4096)
+	l.SetClock(FixedClock(testTime))
+	l.Info("hello")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != "2006-01-02T15:04:05Z\tINFO\t\thello\n" || w.closed != 1 {
+		t.Errorf("got %q closed %d times", w.String(), w.closed)
+	}
+}
+
+func TestCloseDefaultKeepsStderr(t *testing.T) {
+	l := Default()
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stderr.Stat(); err != nil {
+		t.Errorf("stderr closed: %v", err)
+	}
+}
+
+func TestNewSplit(t *testing.T) {
+	tests := []struct {
+		level Level
+		toErr bool
+	}{
+		{TraceLevel, false},
+		{DebugLevel, false},
+		{InfoLevel, false},
+		{WarningLevel, true},
+		{ErrorLevel, true},
+		{PanicLevel, true},
+		{FatalLevel, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			stubExit(t)
+			info, errs := &CaptureBuffer{}, &CaptureBuffer{}
+			l := NewSplit(info, errs)
+			l.SetLevel(TraceLevel)
+			// the split carries through Prefix
+			catchPanic(func() { l.Prefix("p").Log(tt.level, "hello") })
+			want, other := info, errs
+			if tt.toErr {
+				want, other = errs, info
+			}
+			if len(want.Lines()) != 1 || other.String() != "" {
+				t.Errorf("got info %q and errors %q", info.String(), errs.String())
+			}
+		})
+	}
+}
+
+func TestLogDispatch(t *testing.T) {
+	tests := []struct {
+		level  Level
+		method func(l Logger) func(format string, v ...interface{})
+		panics bool
+		exits  bool
+	}{
+		{DebugLevel, func(l Logger) func(string, ...interface{}) { return l.Debug }, false, false},
+		{InfoLevel, func(l Logger) func(string, ...interface{}) { return l.Info }, false, false},
+		{WarningLevel, func(l Logger) func(string, ...interface{}) { return l.Warning }, false, false},
+		{ErrorLevel, func(l Logger) func(string, ...interface{}) { return l.Error }, false, false},
+		{PanicLevel, func(l Logger) func(string, ...interface{}) { return l.Panic }, true, false},
+		{FatalLevel, func(l Logger) func(string, ...interface{}) { return l.Fatal }, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			code := stubExit(t)
+			l, buf := newLogger()
+			l.SetLevel(TraceLevel)
+			v := catchPanic(func() { l.Log(tt.level, "hello %d", 1) })
+			viaLog := buf.String()
+			if (v == "hello 1") != tt.panics || (*code == 1) != tt.exits {
+				t.Errorf("Log panicked with %v and exited with %d", v, *code)
+			}
+
+			buf.Reset()
+			catchPanic(func() { tt.method(l)("hello %d", 1) })
+			if buf.String() != viaLog {
+				t.Errorf("Log wrote %q, the level's method wrote %q", viaLog, buf.String())
+			}
+
+			// NullLogger dispatches the same way
+			*code = -1
+			v = catchPanic(func() { Null().Log(tt.level, "hello %d", 1) })
+			if (v == "hello 1") != tt.panics || (*code == 1) != tt.exits {
+				t.Errorf("NullLogger.Log panicked with %v and exited with %d", v, *code)
+			}
+		})
+	}
+}
+
+func TestHooks(t *testing.T) {
+	type call struct {
+		level       Level
+		prefix, msg string
+	}
+	tests := []struct {
+		level Level
+		log   func(l Logger)
+	}{
+		{DebugLevel, func(l Logger) { l.Debug("hello %d", 1) }},
+		{InfoLevel, func(l Logger) { l.Info("hello %d", 1) }},
+		{WarningLevel, func(l Logger) { l.Warning("hello %d", 1) }},
+		{ErrorLevel, func(l Logger) { l.Error("hello %d", 1) }},
+		{PanicLevel, func(l Logger) { catchPanic(func() { l.Panic("hello %d", 1) }) }},
+		{FatalLevel, func(l Logger) { l.Fatal("hello %d", 1) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			stubExit(t)
+			l, buf := newLogger()
+			l.SetLevel(DebugLevel)
+			var got []call
+			l.AddHook(func(level Level, prefix, msg string) {
+				// the line is written before the hook runs
+				if buf.String() == "" {
+					t.Error("hook called before the line was written")
+				}
+				got = append(got, call{level, prefix, msg})
+			})
+			tt.log(l.Prefix("db"))
+			want := call{tt.level, "db", "hello 1"}
+			if len(got) != 1 || got[0] != want {
+				t.Errorf("hook got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestHooksInheritance(t *testing.T) {
+	l, _ := newLogger()
+	var n int
+	before := l.Prefix("before")
+	l.AddHook(func(Level, string, string) { n++ })
+	l.Info("root")
+	l.Prefix("after").Info("child")
+	before.Info("not hooked")
+	// disabled lines aren't written, so aren't hooked
+	l.Debug("disabled")
+	if n != 2 {
+		t.Errorf("hook called %d times, want 2", n)
+	}
+}
+
+func TestSilentNull(t *testing.T) {
+	code := stubExit(t)
+	var l Logger = SilentNull()
+	// derived loggers stay silent
+	l = l.Prefix("p").With("k", 1)
+	if v := catchPanic(func() {
+		l.Panic("boom")
+		l.Log(PanicLevel, "boom")
+		l.Fatal("the end")
+	}); v != nil {
+		t.Errorf("panicked with %v", v)
+	}
+	if *code != -1 {
+		t.Errorf("exited with %d", *code)
+	}
+	if l.Enabled(PanicLevel) {
+		t.Error("enabled at PanicLevel")
+	}
+
+	// Null still panics
+	if v := catchPanic(func() { Null().Panic("boom") }); v != "boom" {
+		t.Errorf("Null panicked with %v, want boom", v)
+	}
+}
+
+func TestDisabledLevelAllocs(t *testing.T) {
+	l := NewWriter(io.Discard)
+	l.SetLevel(WarningLevel)
+	// through the Logger interface the compiler can't tell that the
+	// arguments don't escape, so it moves them to the heap
+	pl := l.Prefix("p").(*DefaultLogger)
+	n := 42
+	if allocs := testing.AllocsPerRun(100, func() { pl.Debug("n=%d s=%s", n, "x") }); allocs != 0 {
+		t.Errorf("disabled Debug allocated %v times, want none", allocs)
+	}
+}
+
+func BenchmarkInfoDisabled(b *testing.B) {
+	l := NewWriter(io.Discard)
+	l.SetLevel(WarningLevel)
+	n := 42
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Info("n=%d s=%s", n, "x")
+	}
+}
+
+func TestLineTerminator(t *testing.T) {
+	constructors := []struct {
+		name string
+		new  func(w io.Writer) *DefaultLogger
+	}{
+		{"text", NewWriter},
+		{"JSON", NewJSON},
+		{"logfmt", NewLogfmt},
+		{"CSV", NewCSV},
+	}
+	terminators := []struct {
+		name string
+		set  bool
+		eol  string
+		want string
+	}{
+		{"default", false, "", "\n"},
+		{"CRLF", true, "\r\n", "\r\n"},
+		{"none", true, "", ""},
+	}
+	for _, c := range constructors {
+		for _, tt := range terminators {
+			t.Run(c.name+"/"+tt.name, func(t *testing.T) {
+				buf := &CaptureBuffer{}
+				l := c.new(buf)
+				l.SetClock(FixedClock(testTime))
+				if tt.set {
+					l.SetLineTerminator(tt.eol)
+				}
+				// carried through Prefix
+				pl := l.Prefix("p")
+				pl.Info("one")
+				first := buf.String()
+				pl.Info("two")
+				got := buf.String()
+				if !strings.HasSuffix(first, tt.want) ||
+					strings.Count(got, "\n") != 2*strings.Count(tt.want, "\n") ||
+					strings.Count(got, "\r") != 2*strings.Count(tt.want, "\r") ||
+					got[len(first):] != strings.Replace(first, "one", "two", 1) {
+					t.Errorf("got %q, want each line ended by %q", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestOrNull(t *testing.T) {
+	var nilDefault *DefaultLogger
+	var nilTest *TestLogger
+	tests := []struct {
+		name string
+		l    Logger
+	}{
+		{"nil", nil},
+		{"nil DefaultLogger", nilDefault},
+		{"nil TestLogger", nilTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := OrNull(tt.l)
+			if _, ok := l.(*NullLogger); !ok {
+				t.Fatalf("got %T, want *NullLogger", l)
+			}
+			l.Debug("hello")
+			l.Info("hello")
+			l.Warning("hello")
+			l.Error("hello")
+			l.Log(InfoLevel, "hello")
+			l.Must("do it", nil)
+			l.Prefix("p").With("k", 1).Info("hello")
+			if l.Enabled(InfoLevel) {
+				t.Error("enabled at InfoLevel")
+			}
+		})
+	}
+
+	l, _ := newLogger()
+	if got := OrNull(l); got != Logger(l) {
+		t.Errorf("OrNull replaced a non-nil logger with %T", got)
+	}
+}
+
+func TestPushLevel(t *testing.T) {
+	l, buf := newLogger()
+	pl := l.Prefix("p")
+
+	func() {
+		defer l.PushLevel(WarningLevel)()
+		// the threshold is shared with derived loggers
+		pl.Info("quiet")
+		func() {
+			defer pl.(*DefaultLogger).PushLevel(ErrorLevel)()
+			l.Warning("quieter")
+		}()
+		l.Warning("loud again")
+	}()
+	pl.Info("restored")
+
+	want := []string{
+		"2006-01-02T15:04:05Z\tWARN\t\tloud again",
+		"2006-01-02T15:04:05Z\tINFO\tp\trestored",
+	}
+	if got := buf.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPushLevelConcurrent(t *testing.T) {
+	l, _ := newLogger()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				restore := l.PushLevel(ErrorLevel)
+				l.Info("hello")
+				restore()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestTrace(t *testing.T) {
+	tests := []struct {
+		name  string
+		level Level
+		want  string
+	}{
+		{"default threshold", InfoLevel, ""},
+		{"debug", DebugLevel, ""},
+		{"trace", TraceLevel, "2006-01-02T15:04:05Z\tTRACE\tp\tloop 1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			if tt.level != InfoLevel {
+				l.SetLevel(tt.level)
+			}
+			// there is no Trace method, since the Trace field came first
+			l.Prefix("p").Log(TraceLevel, "loop %d", 1)
+			if buf.String() != tt.want {
+				t.Errorf("got %q, want %q", buf.String(), tt.want)
+			}
+			if l.Enabled(TraceLevel) != (tt.want != "") {
+				t.Errorf("Enabled(TraceLevel) = %v", !(tt.want != ""))
+			}
+		})
+	}
+	Null().Log(TraceLevel, "discarded")
+}
+
+func TestClone(t *testing.T) {
+	l, buf := newLogger()
+	pl := l.Prefix("p").(*DefaultLogger)
+	c := pl.Clone()
+	c.SetLevel(DebugLevel)
+	other := &CaptureBuffer{}
+	c.SetOutput(other)
+
+	c.Debug("clone")
+	pl.Debug("parent debug")
+	pl.Info("parent")
+	if got := buf.String(); got != "2006-01-02T15:04:05Z\tINFO\tp\tparent\n" {
+		t.Errorf("parent wrote %q", got)
+	}
+	if got := other.String(); got != "2006-01-02T15:04:05Z\tDEBUG\tp\tclone\n" {
+		t.Errorf("clone wrote %q", got)
+	}
+}
+
+func TestCloneBuffered(t *testing.T) {
+	under := &CaptureBuffer{}
+	l := NewBuffered(under, 4096)
+	l.SetClock(FixedClock(testTime))
+	c := l.Clone()
+
+	// the clone writes into l's buffer, and flushes it on Panic
+	c.Info("one")
+	if under.String() != "" {
+		t.Errorf("got %q before flushing", under.String())
+	}
+	catchPanic(func() { c.Panic("two") })
+	if n := len(under.Lines()); n != 2 {
+		t.Errorf("got %q, want both lines flushed", under.String())
+	}
+
+	// redirecting the clone leaves l writing to its buffer
+	other := &CaptureBuffer{}
+	c.SetOutput(other)
+	c.Info("three")
+	l.Info("four")
+	l.Flush()
+	if got := other.String(); got != "2006-01-02T15:04:05Z\tINFO\t\tthree\n" {
+		t.Errorf("clone wrote %q", got)
+	}
+	if got := under.Lines(); len(got) != 3 || !strings.HasSuffix(got[2], "\tfour") {
+		t.Errorf("parent wrote %q", got)
+	}
+	c.Close()
+	l.Info("five")
+	if err := l.Close(); err != nil || len(under.Lines()) != 4 {
+		t.Errorf("Close = %v, wrote %q", err, under.String())
+	}
+}
+
+func TestMaxMessageLength(t *testing.T) {
+	tests := []struct {
+		name string
+		max  int
+		msg  string
+		want string
+	}{
+		{"no limit", 0, "héllo wörld", "héllo wörld"},
+		{"under", 6, "héllo", "héllo"},
+		{"exactly", 5, "héllo", "héllo"},
+		{"one over", 4, "héllo", "héll…(truncated)"},
+		{"cut after multibyte", 2, "héllo", "hé…(truncated)"},
+		{"all multibyte", 3, "☃☃☃☃", "☃☃☃…(truncated)"},
+		{"four-byte runes", 1, "🙂🙂", "🙂…(truncated)"},
+		{"invalid UTF-8", 2, "a\xffbc", "a\xff…(truncated)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			l.SetMaxMessageLength(tt.max)
+			// inherited through Prefix, and fields don't count
+			l.Prefix("p").With("k", "long value").Info("%s", tt.msg)
+			want := "2006-01-02T15:04:05Z\tINFO\tp\t" + tt.want + "\tk=long value\n"
+			if buf.String() != want {
+				t.Errorf("got %q, want %q", buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestErrMethods(t *testing.T) {
+	errBroken := errors.New("broken pipe")
+	tests := []struct {
+		name string
+		log  func(l *DefaultLogger) error
+	}{
+		{"DebugErr", func(l *DefaultLogger) error { return l.DebugErr("hello") }},
+		{"InfoErr", func(l *DefaultLogger) error { return l.InfoErr("hello") }},
+		{"WarningErr", func(l *DefaultLogger) error { return l.WarningErr("hello") }},
+		{"ErrorErr", func(l *DefaultLogger) error { return l.ErrorErr("hello") }},
+		{"LogErr", func(l *DefaultLogger) error { return l.LogErr(InfoLevel, "hello") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewWriter(errWriter{errBroken})
+			l.SetLevel(DebugLevel)
+			handled := false
+			l.SetErrorHandler(func(error) { handled = true })
+			// the error comes back instead of panicking or going to
+			// the handler
+			if err := tt.log(l); err != errBroken {
+				t.Errorf("got %v, want errBroken", err)
+			}
+			if handled {
+				t.Error("the error handler was called")
+			}
+
+			ok, buf := newLogger()
+			ok.SetLevel(DebugLevel)
+			if err := tt.log(ok); err != nil || len(buf.Lines()) != 1 {
+				t.Errorf("got %v writing %q", err, buf.String())
+			}
+		})
+	}
+}
+
+func TestErrMethodsBelowThreshold(t *testing.T) {
+	l := NewWriter(errWriter{errors.New("broken pipe")})
+	if err := l.DebugErr("hello"); err != nil {
+		t.Errorf("got %v for a line that wasn't written", err)
+	}
+}
+
+func TestLogErrPanics(t *testing.T) {
+	l, _ := newLogger()
+	if v := catchPanic(func() { l.LogErr(PanicLevel, "boom") }); v != "boom" {
+		t.Errorf("panicked with %v, want boom", v)
+	}
+}
+
+func TestNullGuardedAllocs(t *testing.T) {
+	var l Logger = Null()
+	if l.Enabled(DebugLevel) || l.Enabled(ErrorLevel) || !l.Enabled(PanicLevel) {
+		t.Error("NullLogger should only be enabled at PanicLevel and above")
+	}
+	if allocs := testing.AllocsPerRun(100, func() { nullGuarded(l) }); allocs != 0 {
+		t.Errorf("guarded call allocated %v times, want none", allocs)
+	}
+}
+
+// nullGuarded logs expensive data only if l is enabled for it.
+func nullGuarded(l Logger) {
+	if l.Enabled(DebugLevel) {
+		l.Debug("state: %v", []int{1, 2, 3})
+	}
+}
+
+func BenchmarkNullGuarded(b *testing.B) {
+	var l Logger = Null()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		nullGuarded(l)
+	}
+}
+
+func TestTee(t *testing.T) {
+	l, buf := newLogger()
+	l.SetPrefixSeparator("/")
+	mirror := &CaptureBuffer{}
+	tl := l.Tee(mirror)
+	tl.Prefix("a").Prefix("b").With("k", 1).Info("hello")
+
+	want := "2006-01-02T15:04:05Z\tINFO\ta/b\thello\tk=1\n"
+	if buf.String() != want || mirror.String() != want {
+		t.Errorf("got %q and mirrored %q, want %q in both", buf.String(), mirror.String(), want)
+	}
+
+	// l itself isn't mirrored, and the threshold is shared
+	l.Info("only l")
+	l.SetLevel(ErrorLevel)
+	tl.Info("dropped")
+	if len(buf.Lines()) != 2 || len(mirror.Lines()) != 1 {
+		t.Errorf("got %q and mirrored %q", buf.String(), mirror.String())
+	}
+}
+
+func TestTeeJSON(t *testing.T) {
+	l, buf := newJSONLogger()
+	mirror := &CaptureBuffer{}
+	l.Tee(mirror).Warning("hello")
+	if buf.String() == "" || mirror.String() != buf.String() {
+		t.Errorf("got %q and mirrored %q", buf.String(), mirror.String())
+	}
+}
+
+func TestSetLevelWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		level Level
+		split bool
+		want  string // which writer the line goes to
+	}{
+		{"routed", ErrorLevel, false, "errors"},
+		{"routed too", DebugLevel, false, "debug"},
+		{"not routed", InfoLevel, false, "main"},
+		{"not routed above", WarningLevel, false, "main"},
+		{"routed over split", ErrorLevel, true, "errors"},
+		{"split fallback", WarningLevel, true, "split"},
+		{"split info", InfoLevel, true, "main"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bufs := map[string]*CaptureBuffer{"main": {}, "split": {}, "errors": {}, "debug": {}}
+			l := NewWriter(bufs["main"])
+			if tt.split {
+				l = NewSplit(bufs["main"], bufs["split"])
+			}
+			l.SetClock(FixedClock(testTime))
+			l.SetDebug(true)
+			l.SetLevelWriter(ErrorLevel, bufs["errors"])
+			l.SetLevelWriter(DebugLevel, bufs["debug"])
+			l.SetLevelWriter(WarningLevel, bufs["debug"])
+			// nil routes the level back
+			l.SetLevelWriter(WarningLevel, nil)
+			l.Prefix("p").Log(tt.level, "hello")
+			for name, buf := range bufs {
+				want := ""
+				if name == tt.want {
+					want = "2006-01-02T15:04:05Z\t" + tt.level.String() + "\tp\thello\n"
+				}
+				if buf.String() != want {
+					t.Errorf("got %q in %s, want %q", buf.String(), name, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTeeLevelWriters(t *testing.T) {
+	l, buf := newLogger()
+	errs := &CaptureBuffer{}
+	l.SetLevelWriter(ErrorLevel, errs)
+	l.SetLevelWriter(PanicLevel, errs)
+	mirror := &CaptureBuffer{}
+	tl := l.Tee(mirror).Prefix("p")
+	tl.Info("one")
+	tl.Error("two")
+	catchPanic(func() { tl.Panic("three") })
+
+	want := []string{
+		"2006-01-02T15:04:05Z\tINFO\tp\tone",
+		"2006-01-02T15:04:05Z\tERROR\tp\ttwo",
+		"2006-01-02T15:04:05Z\tPANIC\tp\tthree",
+	}
+	if got := mirror.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("mirrored %q, want %q", got, want)
+	}
+	if got := errs.Lines(); strings.Join(got, "\n") != strings.Join(want[1:], "\n") {
+		t.Errorf("got %q in the error writer, want %q", got, want[1:])
+	}
+	if got := buf.Lines(); strings.Join(got, "\n") != want[0] {
+		t.Errorf("got %q in the main writer, want %q", got, want[:1])
+	}
+	// one mirror for the writer both levels share
+	if n := len(tl.(*DefaultLogger).writers(tl.(*DefaultLogger).writer())); n != 2 {
+		t.Errorf("got %d writers, want 2", n)
+	}
+}
+
+func TestDiscardSkipsFormatting(t *testing.T) {
+	code := stubExit(t)
+	l := NewWriter(io.Discard)
+	spy := formatSpy(func() { t.Error("formatted a line going to io.Discard") })
+	l.Prefix("p").Info("%v", spy)
+	if l.Enabled(ErrorLevel) {
+		t.Error("enabled while discarding")
+	}
+
+	// Panic and Fatal still panic and exit
+	if v := catchPanic(func() { l.Panic("boom %d", 1) }); v != "boom 1" {
+		t.Errorf("panicked with %v, want boom 1", v)
+	}
+	l.Fatal("the end")
+	if *code != 1 {
+		t.Errorf("exited with %d, want 1", *code)
+	}
+
+	// a hook still sees the lines
+	var n int
+	l.AddHook(func(Level, string, string) { n++ })
+	l.Info("hooked")
+	if n != 1 {
+		t.Errorf("hook called %d times, want 1", n)
+	}
+}
+
+func BenchmarkDiscard(b *testing.B) {
+	l := NewWriter(io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Info("n=%d s=%s", i, "x")
+	}
+}
+
+func BenchmarkDiscardWriter(b *testing.B) {
+	// a writer that discards, but that the logger can't tell apart
+	// from any other, so every line is formatted
+	l := NewWriter(discardWriter{})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Info("n=%d s=%s", i, "x")
+	}
+}
+
+// discardWriter discards everything, like io.Discard, but isn't it.
+type discardWriter struct{}
+
+func (discardWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func TestPrefixAll(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(l *DefaultLogger)
+		base  []string
+		parts []string
+	}{
+		{"from root", func(*DefaultLogger) {}, nil, []string{"a", "b", "c"}},
+		{"onto a prefix", func(*DefaultLogger) {}, []string{"x"}, []string{"a", "b"}},
+		{"no parts", func(*DefaultLogger) {}, []string{"x"}, nil},
+		{"separator", func(l *DefaultLogger) { l.SetPrefixSeparator("/") }, []string{"x"}, []string{"a", "b"}},
+		{"dedup", func(l *DefaultLogger) { l.SetDedupPrefix(true) }, []string{"x"}, []string{"x", "a", "a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			tt.setup(l)
+			var base Logger = l
+			for _, p := range tt.base {
+				base = base.Prefix(p)
+			}
+			chained := base
+			for _, p := range tt.parts {
+				chained = chained.Prefix(p)
+			}
+			chained.Info("hello")
+			base.(*DefaultLogger).PrefixAll(tt.parts...).Info("hello")
+			if got := buf.Lines(); len(got) != 2 || got[0] != got[1] {
+				t.Errorf("chained Prefix and PrefixAll wrote %q", got)
+			}
+		})
+	}
+}
+
+func BenchmarkPrefixChained(b *testing.B) {
+	l := NewWriter(io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Prefix("http").Prefix("GET").Prefix("/users")
+	}
+}
+
+func BenchmarkPrefixAll(b *testing.B) {
+	l := NewWriter(io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.PrefixAll("http", "GET", "/users")
+	}
+}
+
+func TestNoTimestamp(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func(w io.Writer) *DefaultLogger
+		want string
+	}{
+		{"text", NewWriter, "INFO\tp\thello\n"},
+		{"JSON", NewJSON, `{"level":"INFO","prefix":"p","msg":"hello"}` + "\n"},
+		{"logfmt", NewLogfmt, "level=INFO prefix=p msg=hello\n"},
+		{"CSV", NewCSV, "INFO,p,hello,\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &CaptureBuffer{}
+			l := tt.new(buf)
+			l.SetClock(FixedClock(testTime))
+			l.SetTimestamp(false)
+			// inherited through Prefix
+			l.Prefix("p").Info("hello")
+			if buf.String() != tt.want {
+				t.Errorf("got %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPanicValue(t *testing.T) {
+	l, buf := newLogger()
+	l.SetPanicValue(NewPanicError)
+	before := time.Now()
+	v := catchPanic(func() { l.Prefix("p").Panic("boom %d", 1) })
+	pe, ok := v.(*PanicError)
+	if !ok {
+		t.Fatalf("panicked with %T, want *PanicError", v)
+	}
+	if pe.Message != "boom 1" || pe.Error() != "boom 1" || pe.Time.Before(before) {
+		t.Errorf("got %+v", pe)
+	}
+	var err error = pe
+	if !errors.As(err, &pe) {
+		t.Error("errors.As doesn't find the *PanicError")
+	}
+	if buf.String() != "2006-01-02T15:04:05Z\tPANIC\tp\tboom 1\n" {
+		t.Errorf("wrote %q", buf.String())
+	}
+
+	// a custom value, and nil restoring the default
+	l.SetPanicValue(func(msg string) interface{} { return len(msg) })
+	if v := catchPanic(func() { l.Panic("boom") }); v != 4 {
+		t.Errorf("panicked with %v, want 4", v)
+	}
+	l.SetPanicValue(nil)
+	if v := catchPanic(func() { l.Panic("boom") }); v != "boom" {
+		t.Errorf("panicked with %v, want boom", v)
+	}
+}
+
+// detailedErr is an error with more to say under %+v, like those of
+// github.com/pkg/errors.
+type detailedErr struct{}
+
+func (detailedErr) Error() string {
+	return "short"
+}
+
+func (e detailedErr) Format(s fmt.State, verb rune) {
+	if s.Flag('+') {
+		fmt.Fprint(s, "short\n\tat frame.go:1")
+		return
+	}
+	fmt.Fprint(s, e.Error())
+}
+
+// tracedErr is an error with a stack trace but no Format method.
+type tracedErr struct{}
+
+func (tracedErr) Error() string {
+	return "traced"
+}
+
+func (tracedErr) StackTrace() traceFrames {
+	return nil
+}
+
+// traceFrames stands in for the StackTrace type of
+// github.com/pkg/errors.
+type traceFrames []uintptr
+
+func (traceFrames) Format(s fmt.State, verb rune) {
+	fmt.Fprint(s, "\n\tat trace.go:2")
+}
+
+func TestErrorDetail(t *testing.T) {
+	chain := fmt.Errorf("load config: %w", fmt.Errorf("open: %w", errors.New("no such file")))
+	wrapped := fmt.Errorf("load config: %w", detailedErr{})
+	tests := []struct {
+		name   string
+		detail bool
+		level  Level
+		err    error
+		want   string
+	}{
+		{"off", false, ErrorLevel, detailedErr{}, "failed: short"},
+		{"on", true, ErrorLevel, detailedErr{}, "failed: short\n\tat frame.go:1"},
+		{"below Error", true, WarningLevel, detailedErr{}, "failed: short"},
+		{"wrapped chain", true, ErrorLevel, chain, "failed: load config: open: no such file"},
+		{"wrapped Formatter", true, ErrorLevel, wrapped, "failed: load config: short\n\tat frame.go:1"},
+		{"stack trace", true, ErrorLevel, tracedErr{}, "failed: traced\n\tat trace.go:2"},
+		{"wrapped stack trace", true, ErrorLevel, fmt.Errorf("load: %w", tracedErr{}), "failed: load: traced\n\tat trace.go:2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			l.SetErrorDetail(tt.detail)
+			args := []interface{}{tt.err}
+			// inherited through Prefix
+			l.Prefix("p").Log(tt.level, "failed: %v", args...)
+			if got := buf.String(); !strings.HasSuffix(got, "\tp\t"+tt.want+"\n") {
+				t.Errorf("got %q, want the message %q", got, tt.want)
+			}
+			if args[0] != tt.err {
+				t.Error("changed the caller's arguments")
+			}
+		})
+	}
+}
+
+func TestErrorDetailPanics(t *testing.T) {
+	l, _ := newLogger()
+	l.SetErrorDetail(true)
+	v := catchPanic(func() { l.Panic("failed: %v", detailedErr{}) })
+	if v != "failed: short\n\tat frame.go:1" {
+		t.Errorf("panicked with %q, want the detailed message", v)
+	}
+}
+
+// syncErrWriter is a CaptureBuffer that fails to sync, as a pipe
+// does.
+type syncErrWriter struct {
+	CaptureBuffer
+	syncs int
+}
+
+func (w *syncErrWriter) Sync() error {
+	w.syncs++
+	return errors.New("sync not supported")
+}
+
+func TestPanicSyncFails(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func(w io.Writer) *DefaultLogger
+	}{
+		{"unbuffered", NewWriter},
+		{"buffered", func(w io.Writer) *DefaultLogger { return NewBuffered(w, 4096) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &syncErrWriter{}
+			l := tt.new(w)
+			l.SetClock(FixedClock(testTime))
+			var errs []error
+			l.SetErrorHandler(func(err error) { errs = append(errs, err) })
+			if v := catchPanic(func() { l.Panic("boom %d", 1) }); v != "boom 1" {
+				t.Errorf("panicked with %v, want boom 1", v)
+			}
+			if w.syncs != 1 {
+				t.Errorf("synced %d times, want 1", w.syncs)
+			}
+			if got := w.String(); got != "2006-01-02T15:04:05Z\tPANIC\t\tboom 1\n" {
+				t.Errorf("wrote %q", got)
+			}
+			if len(errs) != 0 {
+				t.Errorf("the sync error was reported as %v", errs)
+			}
+		})
+	}
+}
+
+func TestSeverityPrefix(t *testing.T) {
+	tests := []struct {
+		level    Level
+		severity string
+	}{
+		{TraceLevel, "7"},
+		{DebugLevel, "7"},
+		{InfoLevel, "6"},
+		{WarningLevel, "4"},
+		{ErrorLevel, "3"},
+		{PanicLevel, "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			l, buf := newLogger()
+			l.SetLevel(TraceLevel)
+			l.SetSeverityPrefix(true)
+			catchPanic(func() { l.Prefix("p").Log(tt.level, "hello") })
+			want := tt.severity + "\t2006-01-02T15:04:05Z\t" + tt.level.String() + "\tp\thello\n"
+			if buf.String() != want {
+				t.Errorf("got %q, want %q", buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestSeverityPrefixFatal(t *testing.T) {
+	code := stubExit(t)
+	l, buf := newLogger()
+	l.SetSeverityPrefix(true)
+	l.Fatal("bye")
+	if want := "2\t2006-01-02T15:04:05Z\tFATAL\t\tbye\n"; buf.String() != want || *code != 1 {
+		t.Errorf("got %q and exit %d, want %q", buf.String(), *code, want)
+	}
+}
+
+func TestSeverityPrefixJSON(t *testing.T) {
+	l, buf := newJSONLogger()
+	l.SetSeverityPrefix(true)
+	l.Warning("hello")
+	if want := `{"severity":4,"time":"2006-01-02T15:04:05Z","level":"WARN","prefix":"","msg":"hello"}` + "\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	l, buf := newLogger()
+	calls := 0
+	l.SetHeader(func() string {
+		calls++
+		return "# v1"
+	})
+	pl := l.Prefix("p")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(l Logger) {
+			defer wg.Done()
+			l.Info("hello")
+		}([]Logger{l, pl}[i%2])
+	}
+	wg.Wait()
+	lines := buf.Lines()
+	if len(lines) != 21 || lines[0] != "# v1" || calls != 1 {
+		t.Errorf("got %q from %d calls, want the header once, first", lines, calls)
+	}
+}
+
+func TestHeaderNewFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		new   func(t *testing.T, path string) *DefaultLogger
+		start func(t *testing.T, l *DefaultLogger, path string)
+	}{
+		{"Reopen", newTestFile, func(t *testing.T, l *DefaultLogger, path string) {
+			if err := os.Rename(path, path+".1"); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.Reopen(); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"rotation", newTestRotating, func(t *testing.T, l *DefaultLogger, path string) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			l := tt.new(t, path)
+			defer l.Close()
+			l.SetClock(FixedClock(testTime))
+			l.SetHeader(func() string { return "# v1" })
+			l.Info("one")
+			l.Info("two")
+			tt.start(t, l, path)
+			l.Info("three")
+			want := []string{
+				"# v1\n2006-01-02T15:04:05Z\tINFO\t\tone\n2006-01-02T15:04:05Z\tINFO\t\ttwo\n",
+				"# v1\n2006-01-02T15:04:05Z\tINFO\t\tthree\n",
+			}
+			if got := readFile(t, path+".1"); got != want[0] {
+				t.Errorf("old file: got %q, want %q", got, want[0])
+			}
+			if got := readFile(t, path); got != want[1] {
+				t.Errorf("new file: got %q, want %q", got, want[1])
+			}
+		})
+	}
+}
+
+func TestHeaderExistingFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		new      func(t *testing.T, path string) *DefaultLogger
+		existing string
+		want     string
+	}{
+		{"NewFile", newTestFile, "# v1\nold\n", "# v1\nold\n2006-01-02T15:04:05Z\tINFO\t\tnew\n"},
+		{"NewFile empty", newTestFile, "", "# v1\n2006-01-02T15:04:05Z\tINFO\t\tnew\n"},
+		{"rotating", newTestRotating, "# v1\nold\n", "# v1\nold\n2006-01-02T15:04:05Z\tINFO\t\tnew\n"},
+		{"rotating empty", newTestRotating, "", "# v1\n2006-01-02T15:04:05Z\tINFO\t\tnew\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+				t.Fatal(err)
+			}
+			l := tt.new(t, path)
+			defer l.Close()
+			l.SetClock(FixedClock(testTime))
+			// appended after the header the file already has
+			l.SetHeader(func() string { return "# v1" })
+			l.Info("new")
+			if got := readFile(t, path); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestFile returns a logger made by NewFile for path.
+func newTestFile(t *testing.T, path string) *DefaultLogger {
+	t.Helper()
+	l, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// newTestRotating returns a logger writing to a RotatingWriter for
+// path, with room for the header and two lines.
+func newTestRotating(t *testing.T, path string) *DefaultLogger {
+	t.Helper()
+	w, err := NewRotatingWriter(path, 70, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewWriter(w)
+}
+
+func TestHeaderRemoved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	l := newTestRotating(t, path)
+	defer l.Close()
+	l.SetClock(FixedClock(testTime))
+	l.SetHeader(func() string { return "# v1" })
+	l.Info("one")
+	l.SetHeader(nil)
+	l.Info("two")
+	// rotates
+	l.Info("three")
+	if got, want := readFile(t, path), "2006-01-02T15:04:05Z\tINFO\t\tthree\n"; got != want {
+		t.Errorf("new file: got %q, want %q", got, want)
+	}
+}
+
+func TestSingleLine(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{"plain", "hello", "hello"},
+		{"newlines", "one\ntwo\n", `one\ntwo\n`},
+		{"CRLF", "one\r\ntwo", `one\r\ntwo`},
+		{"tabs", "a\tb", `a\tb`},
+		{"escape already", `C:\new`, `C:\new`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			l.SetSingleLine(true)
+			l.Prefix("p").Info("%s", tt.msg)
+			want := "2006-01-02T15:04:05Z\tINFO\tp\t" + tt.want + "\n"
+			if got := buf.String(); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+			if n := strings.Count(buf.String(), "\n"); n != 1 {
+				t.Errorf("got %d lines, want 1", n)
+			}
+		})
+	}
+}
+
+func TestSingleLineOff(t *testing.T) {
+	l, buf := newLogger()
+	l.Info("one\ntwo")
+	if want := "2006-01-02T15:04:05Z\tINFO\t\tone\ntwo\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestSingleLineStack(t *testing.T) {
+	l, buf := newLogger()
+	l.SetSingleLine(true)
+	l.SetPanicStack(true)
+	catchPanic(func() { l.Panic("one\ntwo") })
+	got := buf.String()
+	if !strings.HasPrefix(got, "2006-01-02T15:04:05Z\tPANIC\t\tone\\ntwo\t") || strings.Count(got, "\n") != 1 {
+		t.Errorf("got %q, want one line with the stack escaped", got)
+	}
+}
+
+func TestCrashWriter(t *testing.T) {
+	errBroken := errors.New("broken pipe")
+	tests := []struct {
+		name string
+		w    io.Writer
+	}{
+		{"working", &CaptureBuffer{}},
+		{"broken", errWriter{errBroken}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewJSON(tt.w)
+			l.SetClock(FixedClock(testTime))
+			crash := &CaptureBuffer{}
+			l.SetCrashWriter(crash)
+			pl := l.Prefix("p").With("k", 1)
+			// the broken writer panics too, being unhandled
+			catchPanic(func() { pl.Error("not a crash") })
+			if v := catchPanic(func() { pl.Panic("boom %d", 1) }); v == nil {
+				t.Fatal("didn't panic")
+			}
+			got := crash.String()
+			// a text line, whatever the main format
+			if !strings.HasPrefix(got, "2006-01-02T15:04:05Z\tPANIC\tp\tboom 1\tk=1\t") {
+				t.Errorf("got %q, want the Panic line", got)
+			}
+			if !strings.Contains(got, "log_test.go:") || !strings.HasSuffix(got, "\n") {
+				t.Errorf("got %q, want the stack", got)
+			}
+		})
+	}
+}
+
+func TestSetOutputConcurrent(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func(w io.Writer) *DefaultLogger
+	}{
+		{"unbuffered", NewWriter},
+		{"buffered", func(w io.Writer) *DefaultLogger { return NewBuffered(w, 256) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bufs := []*CaptureBuffer{{}, {}, {}}
+			l := tt.new(bufs[0])
+			l.SetClock(FixedClock(testTime))
+			pl := l.Prefix("p")
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func(l Logger) {
+					defer wg.Done()
+					for j := 0; j < 200; j++ {
+						l.Info("hello")
+					}
+				}([]Logger{l, pl}[i%2])
+			}
+			for i := 0; i < 100; i++ {
+				l.SetOutput(bufs[i%2])
+			}
+			wg.Wait()
+			// the derived logger follows the swap
+			l.SetOutput(bufs[2])
+			pl.Info("last")
+			l.Flush()
+
+			n := 0
+			for _, b := range bufs {
+				for _, line := range b.Lines() {
+					if line != "2006-01-02T15:04:05Z\tINFO\t\thello" && line != "2006-01-02T15:04:05Z\tINFO\tp\thello" &&
+						line != "2006-01-02T15:04:05Z\tINFO\tp\tlast" {
+						t.Fatalf("got torn line %q", line)
+					}
+					n++
+				}
+			}
+			if n != 801 {
+				t.Errorf("got %d lines, want 801", n)
+			}
+			if got := bufs[2].Lines(); len(got) == 0 || got[len(got)-1] != "2006-01-02T15:04:05Z\tINFO\tp\tlast" {
+				t.Errorf("got %q in the last writer", got)
+			}
+		})
+	}
+}
+
+func TestDedupPrefix(t *testing.T) {
+	tests := []struct {
+		name  string
+		dedup bool
+		parts []string
+		want  string
+	}{
+		{"off", false, []string{"db", "db", "query"}, "db:db:query"},
+		{"repeated", true, []string{"db", "db", "query"}, "db:query"},
+		{"many times", true, []string{"db", "db", "db"}, "db"},
+		{"not consecutive", true, []string{"db", "query", "db"}, "db:query:db"},
+		{"whole segments only", true, []string{"db", "dbx", "x"}, "db:dbx:x"},
+		{"later repeat", true, []string{"http", "db", "db"}, "http:db"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			l.SetDedupPrefix(tt.dedup)
+			var pl Logger = l
+			for _, p := range tt.parts {
+				pl = pl.Prefix(p)
+			}
+			if got := pl.(*DefaultLogger).CurrentPrefix(); got != tt.want {
+				t.Errorf("got prefix %q, want %q", got, tt.want)
+			}
+			pl.Info("hello")
+			if want := "2006-01-02T15:04:05Z\tINFO\t" + tt.want + "\thello\n"; buf.String() != want {
+				t.Errorf("got %q, want %q", buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestOmitEmptyPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		omit   bool
+		prefix string
+		want   string
+	}{
+		{"off, empty", false, "", "2006-01-02T15:04:05Z\tINFO\t\thello\tk=1\n"},
+		{"off, prefixed", false, "p", "2006-01-02T15:04:05Z\tINFO\tp\thello\tk=1\n"},
+		{"on, empty", true, "", "2006-01-02T15:04:05Z\tINFO\thello\tk=1\n"},
+		{"on, prefixed", true, "p", "2006-01-02T15:04:05Z\tINFO\tp\thello\tk=1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			l.SetOmitEmptyPrefix(tt.omit)
+			var pl Logger = l
+			if tt.prefix != "" {
+				pl = l.Prefix(tt.prefix)
+			}
+			pl.With("k", 1).Info("hello")
+			if buf.String() != tt.want {
+				t.Errorf("got %q, want %q", buf.String(), tt.want)
+			}
+			if strings.Contains(buf.String(), "\t\t") != (!tt.omit && tt.prefix == "") {
+				t.Errorf("got %q, with the wrong empty columns", buf.String())
+			}
+		})
+	}
+}
+
+func TestOmitEmptyPrefixOtherFormats(t *testing.T) {
+	l, buf := newJSONLogger()
+	l.SetOmitEmptyPrefix(true)
+	l.Info("hello")
+	if want := `{"time":"2006-01-02T15:04:05Z","level":"INFO","prefix":"","msg":"hello"}` + "\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,40 @@
+// Package logexpvar publishes the line counts of a log.DefaultLogger
+// through expvar, so that they appear on /debug/vars. It is a
+// package of its own so that importing the log package doesn't
+// register expvar's handler on http.DefaultServeMux.
+package logexpvar
+
+import "fmt"
+import "sync"
+import "expvar"
+
+import "github.com/ispace-charrington/log"
+
+// mu makes checking whether an expvar name is taken and taking it
+// one step.
+var mu sync.Mutex
+
+// RegisterExpvar publishes the number of lines l has written at each
+// level as the expvar name, as an object such as {"INFO": 12,
+// "ERROR": 1}. It turns on counting, as SetCountingEnabled does, if
+// it is off, and the published counts are l's, shared with the
+// loggers derived from l; turning counting off later publishes none.
+// Since expvar names can't be unpublished, it returns an error
+// rather than replacing a variable already published as name.
+func RegisterExpvar(l *log.DefaultLogger, name string) error {
+	l.SetCountingEnabled(true)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("logexpvar: expvar %q is already published", name)
+	}
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		counts := make(map[string]uint64)
+		for level, n := range l.Counts() {
+			counts[level.String()] = n
+		}
+		return counts
+	}))
+	return nil
+}
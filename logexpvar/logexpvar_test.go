@@ -0,0 +1,55 @@
+package logexpvar
+
+import "expvar"
+import "testing"
+import "encoding/json"
+
+import "github.com/ispace-charrington/log"
+
+func TestRegisterExpvar(t *testing.T) {
+	l, _ := log.NewBuffer()
+	if err := RegisterExpvar(l, "logexpvar_test_counts"); err != nil {
+		t.Fatal(err)
+	}
+	pl := l.Prefix("p")
+	l.Info("one")
+	pl.Info("two")
+	pl.Error("three")
+	l.Debug("hidden")
+
+	var got map[string]uint64
+	if err := json.Unmarshal([]byte(expvar.Get("logexpvar_test_counts").String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["INFO"] != 2 || got["ERROR"] != 1 {
+		t.Errorf("got %v, want 2 INFO and 1 ERROR", got)
+	}
+}
+
+func TestRegisterExpvarTaken(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func()
+	}{
+		{"by RegisterExpvar", func() {
+			l, _ := log.NewBuffer()
+			RegisterExpvar(l, "logexpvar_test_taken")
+		}},
+		{"by something else", func() { expvar.NewInt("logexpvar_test_other") }},
+	}
+	names := []string{"logexpvar_test_taken", "logexpvar_test_other"}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			before := expvar.Get(names[i]).String()
+			l, _ := log.NewBuffer()
+			if err := RegisterExpvar(l, names[i]); err == nil {
+				t.Error("got nil, want an error")
+			}
+			l.Info("hello")
+			if got := expvar.Get(names[i]).String(); got != before {
+				t.Errorf("got %s, want %s still published", got, before)
+			}
+		})
+	}
+}
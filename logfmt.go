@@ -0,0 +1,87 @@
+package log
+
+import "io"
+import "fmt"
+import "strings"
+import "strconv"
+import "unicode"
+
+// NewLogfmt returns a logger that writes logfmt lines to w, like
+//
+//	time=2006-01-02T15:04:05Z level=INFO prefix=db msg="query done" rows=3
+//
+// with any fields added with With as further key=value pairs.
+func NewLogfmt(w io.Writer) (l *DefaultLogger) {
+	l = NewWriter(w)
+	l.format = formatLogfmt
+	return
+}
+
+// logfmtLine renders e as logfmt. The time, caller and stack keys
+// are only present if enabled.
+func logfmtLine(e entry) []byte {
+	var b strings.Builder
+	if e.severity {
+		logfmtPair(&b, "severity", strconv.Itoa(e.level.Severity()))
+	}
+	if e.time != "" {
+		logfmtPair(&b, "time", e.time)
+	}
+	logfmtPair(&b, "level", e.level.String())
+	logfmtPair(&b, "prefix", e.prefix)
+	if e.caller != "" {
+		logfmtPair(&b, "caller", e.caller)
+	}
+	logfmtPair(&b, "msg", e.msg)
+	for _, f := range e.fields {
+		logfmtPair(&b, f.key, fmt.Sprint(f.value))
+	}
+	if e.stack != "" {
+		logfmtPair(&b, "stack", e.stack)
+	}
+	return []byte(b.String())
+}
+
+// logfmtPair writes key=value to b, separated from anything before
+// it by a space.
+func logfmtPair(b *strings.Builder, key, value string) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(logfmtKey(key))
+	b.WriteByte('=')
+	if logfmtNeedsQuote(value) {
+		b.WriteString(strconv.Quote(value))
+	} else {
+		b.WriteString(value)
+	}
+}
+
+// logfmtKey replaces the characters that can't appear in a bare
+// logfmt key with underscores.
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == unicode.ReplacementChar {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// logfmtNeedsQuote reports whether value must be quoted: if it is
+// empty, or contains spaces, equals signs, quotes or anything
+// unprintable.
+func logfmtNeedsQuote(value string) bool {
+	if value == "" {
+		return true
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
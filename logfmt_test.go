@@ -0,0 +1,36 @@
+package log
+
+import "testing"
+
+func TestLogfmt(t *testing.T) {
+	tests := []struct {
+		name  string
+		msg   string
+		key   string
+		value interface{}
+		want  string
+	}{
+		{"bare", "done", "rows", 3, `msg=done rows=3`},
+		{"spaces", "query done", "sql", "select 1", `msg="query done" sql="select 1"`},
+		{"quotes", `say "hi"`, "q", `"`, `msg="say \"hi\"" q="\""`},
+		{"equals", "a=b", "k", "x=y", `msg="a=b" k="x=y"`},
+		{"backslash", `C:\path`, "k", `\`, `msg="C:\\path" k="\\"`},
+		{"newline", "one\ntwo", "k", "\t", `msg="one\ntwo" k="\t"`},
+		{"empty", "", "k", "", `msg="" k=""`},
+		{"unicode", "snow☃", "k", "ünï", `msg=snow☃ k=ünï`},
+		{"bad key", "done", `a b="c`, 1, `msg=done a_b__c=1`},
+		{"empty key", "done", "", 1, `msg=done _=1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &CaptureBuffer{}
+			l := NewLogfmt(buf)
+			l.SetClock(FixedClock(testTime))
+			l.Prefix("db").With(tt.key, tt.value).Info("%s", tt.msg)
+			want := "time=2006-01-02T15:04:05Z level=INFO prefix=db " + tt.want + "\n"
+			if buf.String() != want {
+				t.Errorf("got %q, want %q", buf.String(), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package log
+
+import "fmt"
+
+// multiLogger fans each call out to several loggers.
+type multiLogger []Logger
+
+// Multi returns a Logger that forwards every call to each of the
+// given loggers, in order.
+func Multi(loggers ...Logger) Logger {
+	m := make(multiLogger, len(loggers))
+	copy(m, loggers)
+	return m
+}
+
+// A fatalLogger can write a Fatal line without exiting.
+type fatalLogger interface {
+	fatal(t string)
+}
+
+// Debug forwards to every logger.
+func (m multiLogger) Debug(format string, v ...interface{}) {
+	for _, l := range m {
+		l.Debug(format, v...)
+	}
+}
+
+// Info forwards to every logger.
+func (m multiLogger) Info(format string, v ...interface{}) {
+	for _, l := range m {
+		l.Info(format, v...)
+	}
+}
+
+// Warning forwards to every logger.
+func (m multiLogger) Warning(format string, v ...interface{}) {
+	for _, l := range m {
+		l.Warning(format, v...)
+	}
+}
+
+// Error forwards to every logger.
+func (m multiLogger) Error(format string, v ...interface{}) {
+	for _, l := range m {
+		l.Error(format, v...)
+	}
+}
+
+// Panic forwards to every logger, recovering the panic each of them
+// raises, and then panics once with the formatted message.
+func (m multiLogger) Panic(format string, v ...interface{}) {
+	for _, l := range m {
+		func() {
+			defer func() { recover() }()
+			l.Panic(format, v...)
+		}()
+	}
+	panic(fmt.Sprintf(format, v...))
+}
+
+// Fatal writes the line to every logger and then exits the process
+// with status 1. Loggers from this package write their FATAL line
+// without exiting; any other Logger can't be stopped from exiting,
+// so it gets the line at Error level instead.
+func (m multiLogger) Fatal(format string, v ...interface{}) {
+	t := fmt.Sprintf(format, v...)
+	for _, l := range m {
+		if fl, ok := l.(fatalLogger); ok {
+			fl.fatal(t)
+		} else {
+			l.Error("%s", t)
+		}
+	}
+	osExit(1)
+}
+
+// Log forwards to every logger, except that at PanicLevel and
+// FatalLevel it behaves like Panic and Fatal.
+func (m multiLogger) Log(level Level, format string, v ...interface{}) {
+	switch {
+	case level >= FatalLevel:
+		m.Fatal(format, v...)
+	case level == PanicLevel:
+		m.Panic(format, v...)
+	default:
+		for _, l := range m {
+			l.Log(level, format, v...)
+		}
+	}
+}
+
+// Enabled reports whether any of the loggers is enabled for level.
+func (m multiLogger) Enabled(level Level) bool {
+	for _, l := range m {
+		if l.Enabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Must calls m.Panic() if err is not nil, and otherwise forwards to
+// every logger so that each can trace the success as it sees fit.
+func (m multiLogger) Must(message string, err error) {
+	if err != nil {
+		m.Panic("Failed to %s: %v", message, err)
+	}
+	for _, l := range m {
+		l.Must(message, nil)
+	}
+}
+
+// Prefix returns a new Multi of each logger with prefix appended.
+func (m multiLogger) Prefix(prefix string) Logger {
+	nm := make(multiLogger, len(m))
+	for i, l := range m {
+		nm[i] = l.Prefix(prefix)
+	}
+	return nm
+}
+
+// With returns a new Multi of each logger with the field added.
+func (m multiLogger) With(key string, value interface{}) Logger {
+	nm := make(multiLogger, len(m))
+	for i, l := range m {
+		nm[i] = l.With(key, value)
+	}
+	return nm
+}
@@ -0,0 +1,67 @@
+package log
+
+import "testing"
+
+func TestMulti(t *testing.T) {
+	tests := []struct {
+		name  string
+		log   func(l Logger)
+		level Level
+	}{
+		{"Debug", func(l Logger) { l.Debug("hello %d", 1) }, DebugLevel},
+		{"Info", func(l Logger) { l.Info("hello %d", 1) }, InfoLevel},
+		{"Warning", func(l Logger) { l.Warning("hello %d", 1) }, WarningLevel},
+		{"Error", func(l Logger) { l.Error("hello %d", 1) }, ErrorLevel},
+		{"Log", func(l Logger) { l.Log(WarningLevel, "hello %d", 1) }, WarningLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := NewTest(), NewTest()
+			tt.log(Multi(a, b).Prefix("p").With("k", "v"))
+			for i, tl := range []*TestLogger{a, b} {
+				got := tl.Messages()
+				if len(got) != 1 {
+					t.Fatalf("logger %d got %d entries, want 1", i, len(got))
+				}
+				e := got[0]
+				if e.Level != tt.level || e.Prefix != "p" || e.Message() != "hello 1" || e.Fields["k"] != "v" {
+					t.Errorf("logger %d got %+v", i, e)
+				}
+			}
+		})
+	}
+}
+
+func TestMultiPanicsOnce(t *testing.T) {
+	a, b := NewTest(), NewTest()
+	d, buf := newLogger()
+	v := catchPanic(func() { Multi(a, d, b).Panic("boom %d", 1) })
+	if v != "boom 1" {
+		t.Errorf("panicked with %v, want boom 1", v)
+	}
+	for i, tl := range []*TestLogger{a, b} {
+		if got := tl.Messages(); len(got) != 1 || got[0].Level != PanicLevel {
+			t.Errorf("logger %d got %+v, want one Panic", i, got)
+		}
+	}
+	if want := "2006-01-02T15:04:05Z\tPANIC\t\tboom 1\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestMultiFatalExitsOnce(t *testing.T) {
+	code := stubExit(t)
+	exits := 0
+	osExit = func(c int) { exits++; *code = c }
+	l1, buf1 := newLogger()
+	l2, buf2 := newLogger()
+	Multi(l1, l2).Fatal("bye")
+	if exits != 1 || *code != 1 {
+		t.Errorf("exited %d times with %d, want once with 1", exits, *code)
+	}
+	for _, buf := range []*CaptureBuffer{buf1, buf2} {
+		if want := "2006-01-02T15:04:05Z\tFATAL\t\tbye\n"; buf.String() != want {
+			t.Errorf("got %q, want %q", buf.String(), want)
+		}
+	}
+}
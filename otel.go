@@ -0,0 +1,179 @@
+//go:build otel
+
+package log
+
+import "fmt"
+import "time"
+import "context"
+
+import "go.opentelemetry.io/otel/attribute"
+import otellog "go.opentelemetry.io/otel/log"
+
+// This file needs the OpenTelemetry logs API, so that the rest of
+// the package doesn't: build with -tags otel to include it.
+
+// An OTelLogger emits each line as an OpenTelemetry log record, for
+// export to a collector by the SDK the provider belongs to. The
+// record's body is the message, its severity is mapped from the
+// level, and its attributes are the prefix, as log.prefix, and the
+// fields added with With.
+type OTelLogger struct {
+	l      otellog.Logger
+	prefix string
+	fields []field
+}
+
+// NewOTel returns a logger emitting records through the logger that
+// provider returns for name, which becomes the records'
+// instrumentation scope. Records are exported as the provider is
+// configured to, so shut the provider down before exiting to export
+// the last of them: Fatal can't, since it exits straight away.
+func NewOTel(provider otellog.LoggerProvider, name string) *OTelLogger {
+	return &OTelLogger{l: provider.Logger(name)}
+}
+
+// otelSeverity maps level onto the OpenTelemetry severity scale.
+// Panic and Fatal are both fatal, Fatal as the most severe.
+func otelSeverity(level Level) otellog.Severity {
+	switch {
+	case level >= FatalLevel:
+		return otellog.SeverityFatal4
+	case level >= PanicLevel:
+		return otellog.SeverityFatal
+	case level >= ErrorLevel:
+		return otellog.SeverityError
+	case level >= WarningLevel:
+		return otellog.SeverityWarn
+	case level >= InfoLevel:
+		return otellog.SeverityInfo
+	case level >= DebugLevel:
+		return otellog.SeverityDebug
+	}
+	return otellog.SeverityTrace
+}
+
+// otelValue converts a field value into an attribute value, keeping
+// the basic types and formatting anything else with fmt.
+func otelValue(v interface{}) attribute.Value {
+	switch v := v.(type) {
+	case string:
+		return attribute.StringValue(v)
+	case bool:
+		return attribute.BoolValue(v)
+	case int:
+		return attribute.IntValue(v)
+	case int64:
+		return attribute.Int64Value(v)
+	case float64:
+		return attribute.Float64Value(v)
+	case time.Duration:
+		return attribute.StringValue(v.String())
+	case error:
+		return attribute.StringValue(v.Error())
+	}
+	return attribute.StringValue(fmt.Sprint(v))
+}
+
+// emit builds the record for a line and emits it.
+func (o *OTelLogger) emit(level Level, msg string) {
+	var r otellog.Record
+	r.SetTimestamp(time.Now())
+	r.SetSeverity(otelSeverity(level))
+	r.SetSeverityText(level.String())
+	r.SetBody(attribute.StringValue(msg))
+	if o.prefix != "" {
+		r.AddAttributes(attribute.String("log.prefix", o.prefix))
+	}
+	for _, f := range o.fields {
+		r.AddAttributes(attribute.KeyValue{Key: attribute.Key(f.jsonKey()), Value: otelValue(f.value)})
+	}
+	o.l.Emit(context.Background(), r)
+}
+
+// Log emits the line if the provider wants records at its severity.
+// At PanicLevel it then panics, and at FatalLevel or above it exits.
+func (o *OTelLogger) Log(level Level, format string, v ...interface{}) {
+	switch {
+	case level >= FatalLevel:
+		o.emit(level, fmt.Sprintf(format, v...))
+		osExit(1)
+	case level == PanicLevel:
+		t := fmt.Sprintf(format, v...)
+		o.emit(level, t)
+		panic(t)
+	case o.Enabled(level):
+		o.emit(level, fmt.Sprintf(format, v...))
+	}
+}
+
+// Debug emits the line at debug severity.
+func (o *OTelLogger) Debug(format string, v ...interface{}) {
+	o.Log(DebugLevel, format, v...)
+}
+
+// Info emits the line at info severity.
+func (o *OTelLogger) Info(format string, v ...interface{}) {
+	o.Log(InfoLevel, format, v...)
+}
+
+// Warning emits the line at warn severity.
+func (o *OTelLogger) Warning(format string, v ...interface{}) {
+	o.Log(WarningLevel, format, v...)
+}
+
+// Error emits the line at error severity.
+func (o *OTelLogger) Error(format string, v ...interface{}) {
+	o.Log(ErrorLevel, format, v...)
+}
+
+// Panic emits the line at fatal severity, and then panics.
+func (o *OTelLogger) Panic(format string, v ...interface{}) {
+	o.Log(PanicLevel, format, v...)
+}
+
+// Fatal emits the line at the highest fatal severity, and then exits
+// the process with status 1.
+func (o *OTelLogger) Fatal(format string, v ...interface{}) {
+	o.Log(FatalLevel, format, v...)
+}
+
+// Enabled reports whether the provider wants records at the severity
+// for level.
+func (o *OTelLogger) Enabled(level Level) bool {
+	return o.l.Enabled(context.Background(), otellog.EnabledParameters{Severity: otelSeverity(level)})
+}
+
+// Must calls o.Panic() if err is not nil.
+func (o *OTelLogger) Must(message string, err error) {
+	if err != nil {
+		o.Panic("Failed to %s: %v", message, err)
+	}
+}
+
+// CurrentPrefix returns the prefix accumulated by Prefix calls so
+// far.
+func (o *OTelLogger) CurrentPrefix() string {
+	return o.prefix
+}
+
+// Prefix returns a new OTelLogger with this prefix appended to the
+// log.prefix attribute.
+func (o *OTelLogger) Prefix(prefix string) Logger {
+	no := *o
+	if o.prefix == "" {
+		no.prefix = prefix
+	} else {
+		no.prefix = o.prefix + ":" + prefix
+	}
+	return &no
+}
+
+// With returns a new OTelLogger that adds key=value to the
+// attributes of each record.
+func (o *OTelLogger) With(key string, value interface{}) Logger {
+	no := *o
+	no.fields = append(o.fields[:len(o.fields):len(o.fields)], field{key: key, value: value})
+	return &no
+}
+
+var _ Logger = (*OTelLogger)(nil)
@@ -0,0 +1,130 @@
+//go:build otel
+
+package log
+
+import "sync"
+import "testing"
+import "context"
+
+import "go.opentelemetry.io/otel/attribute"
+import otellog "go.opentelemetry.io/otel/log"
+import "go.opentelemetry.io/otel/log/embedded"
+
+// memoryProvider is an in-memory stand-in for the SDK, recording the
+// records emitted at or above min.
+type memoryProvider struct {
+	embedded.LoggerProvider
+	mu      sync.Mutex
+	min     otellog.Severity
+	scope   string
+	records []otellog.Record
+}
+
+func (p *memoryProvider) Logger(name string, options ...otellog.LoggerOption) otellog.Logger {
+	p.scope = name
+	return memoryLogger{p: p}
+}
+
+type memoryLogger struct {
+	embedded.Logger
+	p *memoryProvider
+}
+
+func (l memoryLogger) Emit(ctx context.Context, r otellog.Record) {
+	l.p.mu.Lock()
+	defer l.p.mu.Unlock()
+	l.p.records = append(l.p.records, r.Clone())
+}
+
+func (l memoryLogger) Enabled(ctx context.Context, param otellog.EnabledParameters) bool {
+	return param.Severity >= l.p.min
+}
+
+// attrs returns the attributes of r by key.
+func attrs(r otellog.Record) map[string]attribute.Value {
+	m := map[string]attribute.Value{}
+	r.WalkAttributes(func(kv attribute.KeyValue) bool {
+		m[string(kv.Key)] = kv.Value
+		return true
+	})
+	return m
+}
+
+func TestOTelSeverity(t *testing.T) {
+	tests := []struct {
+		level    Level
+		severity otellog.Severity
+	}{
+		{TraceLevel, otellog.SeverityTrace},
+		{DebugLevel, otellog.SeverityDebug},
+		{InfoLevel, otellog.SeverityInfo},
+		{WarningLevel, otellog.SeverityWarn},
+		{ErrorLevel, otellog.SeverityError},
+		{PanicLevel, otellog.SeverityFatal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			p := &memoryProvider{}
+			l := NewOTel(p, "app")
+			catchPanic(func() { l.Log(tt.level, "hello %d", 1) })
+			if len(p.records) != 1 {
+				t.Fatalf("got %d records, want 1", len(p.records))
+			}
+			r := p.records[0]
+			if r.Severity() != tt.severity || r.SeverityText() != tt.level.String() || r.Body().AsString() != "hello 1" {
+				t.Errorf("got %v %q %q", r.Severity(), r.SeverityText(), r.Body().AsString())
+			}
+			if r.Timestamp().IsZero() {
+				t.Error("got no timestamp")
+			}
+		})
+	}
+}
+
+func TestOTelFatal(t *testing.T) {
+	code := stubExit(t)
+	p := &memoryProvider{}
+	NewOTel(p, "app").Fatal("bye")
+	if len(p.records) != 1 || p.records[0].Severity() != otellog.SeverityFatal4 || *code != 1 {
+		t.Errorf("got %d records and exit %d", len(p.records), *code)
+	}
+}
+
+func TestOTelAttributes(t *testing.T) {
+	p := &memoryProvider{}
+	l := NewOTel(p, "app")
+	l.Prefix("db").Prefix("query").With("rows", 3).With("ok", true).Info("done")
+	l.Info("plain")
+	if p.scope != "app" {
+		t.Errorf("got scope %q, want app", p.scope)
+	}
+	if len(p.records) != 2 {
+		t.Fatalf("got %d records, want 2", len(p.records))
+	}
+	got := attrs(p.records[0])
+	if len(got) != 3 || got["log.prefix"].AsString() != "db:query" ||
+		got["rows"].AsInt64() != 3 || !got["ok"].AsBool() {
+		t.Errorf("got attributes %v", got)
+	}
+	// no prefix attribute without a prefix, and no fields leaking
+	if got := attrs(p.records[1]); len(got) != 0 {
+		t.Errorf("got attributes %v, want none", got)
+	}
+}
+
+func TestOTelEnabled(t *testing.T) {
+	p := &memoryProvider{min: otellog.SeverityWarn}
+	l := NewOTel(p, "app")
+	l.Info("dropped")
+	l.Warning("kept")
+	if l.Enabled(InfoLevel) || !l.Enabled(ErrorLevel) {
+		t.Error("Enabled doesn't follow the provider")
+	}
+	// Panic emits whatever the provider wants
+	if v := catchPanic(func() { l.Panic("boom") }); v != "boom" {
+		t.Errorf("panicked with %v, want boom", v)
+	}
+	if len(p.records) != 2 || p.records[0].Body().AsString() != "kept" {
+		t.Errorf("got %d records", len(p.records))
+	}
+}
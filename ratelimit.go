@@ -0,0 +1,239 @@
+package log
+
+import "sort"
+import "sync"
+import "time"
+
+// A RateLimitedLogger forwards at most a fixed number of lines per
+// level in each window of time to another Logger, and drops the
+// rest. Panic and Fatal are never dropped.
+type RateLimitedLogger struct {
+	l   Logger
+	lim *limiter
+}
+
+// limiter keeps the per-level windows for a tree of
+// RateLimitedLoggers.
+type limiter struct {
+	mu      sync.Mutex
+	l       Logger
+	max     int
+	per     time.Duration
+	clock   Clock
+	windows map[Level]*rateWindow
+	stats   *stats
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// rateWindow counts the lines seen at one level since start.
+type rateWindow struct {
+	start   time.Time
+	n       int
+	dropped int
+}
+
+// RateLimited returns a logger that forwards at most max lines per
+// level to l in each period of length per. Once a period in which
+// lines were dropped is over, a line at that level saying how many
+// were suppressed is written to l: before the next line at the
+// level, or at the latest by the check made every per. Call Close
+// to stop the checks and report the lines suppressed since. Loggers
+// derived via Prefix and With share the limits, and their summaries
+// are written to l itself.
+func RateLimited(l Logger, max int, per time.Duration) *RateLimitedLogger {
+	t := time.NewTicker(per)
+	return newRateLimited(l, max, per, t.C, t.Stop)
+}
+
+// newRateLimited returns a RateLimitedLogger that reports the periods
+// that are over whenever tick delivers, and calls stopTick once it
+// stops listening, so that tests can drive the reports.
+func newRateLimited(l Logger, max int, per time.Duration, tick <-chan time.Time, stopTick func()) *RateLimitedLogger {
+	lim := &limiter{
+		l:       l,
+		max:     max,
+		per:     per,
+		windows: make(map[Level]*rateWindow),
+		stats:   &stats{},
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go lim.run(tick, stopTick)
+	return &RateLimitedLogger{l: l, lim: lim}
+}
+
+// SetClock replaces the system clock used to measure the periods.
+func (r *RateLimitedLogger) SetClock(c Clock) {
+	r.lim.mu.Lock()
+	defer r.lim.mu.Unlock()
+	r.lim.clock = c
+}
+
+// Stats returns the number of lines forwarded and dropped so far by
+// r and the loggers derived from it.
+func (r *RateLimitedLogger) Stats() Stats {
+	return r.lim.stats.snapshot()
+}
+
+// Flush writes the number of lines suppressed at each level that
+// haven't been reported yet, without waiting for their period to be
+// over.
+func (r *RateLimitedLogger) Flush() {
+	r.lim.report(true)
+}
+
+// Close stops the checks for periods that are over, and then reports
+// the lines suppressed since the last summary, as Flush does.
+func (r *RateLimitedLogger) Close() error {
+	r.lim.once.Do(func() { close(r.lim.stop) })
+	<-r.lim.done
+	r.Flush()
+	return nil
+}
+
+// now returns the current time. The caller must hold lim.mu.
+func (lim *limiter) now() time.Time {
+	if lim.clock == nil {
+		return time.Now()
+	}
+	return lim.clock.Now()
+}
+
+func (lim *limiter) run(tick <-chan time.Time, stopTick func()) {
+	defer close(lim.done)
+	defer stopTick()
+	for {
+		select {
+		case <-tick:
+			lim.report(false)
+		case <-lim.stop:
+			return
+		}
+	}
+}
+
+// report writes a summary line for each level that dropped lines in
+// a period that is over, or with all set, in the current period too.
+func (lim *limiter) report(all bool) {
+	type summary struct {
+		level Level
+		n     int
+	}
+	var sums []summary
+	lim.mu.Lock()
+	now := lim.now()
+	for level, w := range lim.windows {
+		over := now.Sub(w.start) >= lim.per
+		if w.dropped > 0 && (over || all) {
+			sums = append(sums, summary{level, w.dropped})
+			w.dropped = 0
+		}
+		if over {
+			delete(lim.windows, level)
+		}
+	}
+	lim.mu.Unlock()
+
+	sort.Slice(sums, func(i, j int) bool { return sums[i].level < sums[j].level })
+	for _, s := range sums {
+		lim.l.Log(s.level, "%d messages suppressed", s.n)
+	}
+}
+
+// allow reports whether a line at level may be forwarded, and how
+// many lines were suppressed in the period that just ended, if one
+// did and they weren't reported yet.
+func (lim *limiter) allow(level Level) (ok bool, suppressed int) {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	now := lim.now()
+	w := lim.windows[level]
+	if w == nil {
+		w = &rateWindow{start: now}
+		lim.windows[level] = w
+	}
+	if now.Sub(w.start) >= lim.per {
+		suppressed = w.dropped
+		*w = rateWindow{start: now}
+	}
+	if w.n >= lim.max {
+		w.dropped++
+		return false, suppressed
+	}
+	w.n++
+	return true, suppressed
+}
+
+// Log forwards to the wrapped logger unless over the limit for
+// level. PanicLevel and above are always forwarded.
+func (r *RateLimitedLogger) Log(level Level, format string, v ...interface{}) {
+	if level >= PanicLevel {
+		r.lim.stats.count(level, true)
+		r.l.Log(level, format, v...)
+		return
+	}
+	ok, suppressed := r.lim.allow(level)
+	r.lim.stats.count(level, ok)
+	if suppressed > 0 {
+		r.lim.l.Log(level, "%d messages suppressed", suppressed)
+	}
+	if ok {
+		r.l.Log(level, format, v...)
+	}
+}
+
+// Debug forwards to the wrapped logger unless over the limit.
+func (r *RateLimitedLogger) Debug(format string, v ...interface{}) {
+	r.Log(DebugLevel, format, v...)
+}
+
+// Info forwards to the wrapped logger unless over the limit.
+func (r *RateLimitedLogger) Info(format string, v ...interface{}) {
+	r.Log(InfoLevel, format, v...)
+}
+
+// Warning forwards to the wrapped logger unless over the limit.
+func (r *RateLimitedLogger) Warning(format string, v ...interface{}) {
+	r.Log(WarningLevel, format, v...)
+}
+
+// Error forwards to the wrapped logger unless over the limit.
+func (r *RateLimitedLogger) Error(format string, v ...interface{}) {
+	r.Log(ErrorLevel, format, v...)
+}
+
+// Panic always forwards to the wrapped logger.
+func (r *RateLimitedLogger) Panic(format string, v ...interface{}) {
+	r.Log(PanicLevel, format, v...)
+}
+
+// Fatal always forwards to the wrapped logger.
+func (r *RateLimitedLogger) Fatal(format string, v ...interface{}) {
+	r.Log(FatalLevel, format, v...)
+}
+
+// Enabled reports whether the wrapped logger is enabled for level.
+func (r *RateLimitedLogger) Enabled(level Level) bool {
+	return r.l.Enabled(level)
+}
+
+// Must forwards to the wrapped logger.
+func (r *RateLimitedLogger) Must(message string, err error) {
+	r.l.Must(message, err)
+}
+
+// Prefix returns a rate limited logger around the wrapped logger's
+// Prefix, sharing r's limits.
+func (r *RateLimitedLogger) Prefix(prefix string) Logger {
+	return &RateLimitedLogger{l: r.l.Prefix(prefix), lim: r.lim}
+}
+
+// With returns a rate limited logger around the wrapped logger's
+// With, sharing r's limits.
+func (r *RateLimitedLogger) With(key string, value interface{}) Logger {
+	return &RateLimitedLogger{l: r.l.With(key, value), lim: r.lim}
+}
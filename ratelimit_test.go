@@ -0,0 +1,164 @@
+package log
+
+import "time"
+import "testing"
+import "strings"
+
+// newTestRateLimited returns a rate limited logger around l on a
+// test clock, the clock, and a func that makes it report the periods
+// that are over.
+func newTestRateLimited(t *testing.T, l Logger, max int, per time.Duration) (*RateLimitedLogger, *testClock, func()) {
+	t.Helper()
+	tick, check := newManualTick()
+	r := newRateLimited(l, max, per, tick, func() {})
+	c := newTestClock()
+	r.SetClock(c)
+	t.Cleanup(func() { r.Close() })
+	return r, c, check
+}
+
+// levelMessages returns the level and message of each entry tl has.
+func levelMessages(tl *TestLogger) []string {
+	var got []string
+	for _, e := range tl.Messages() {
+		got = append(got, e.Level.String()+" "+e.Message())
+	}
+	return got
+}
+
+func TestRateLimited(t *testing.T) {
+	tl := NewTest()
+	tl.SetPanic(false)
+	r, c, _ := newTestRateLimited(t, tl, 2, time.Second)
+	pl := r.Prefix("p")
+
+	for i := 0; i < 5; i++ {
+		pl.Warning("flood %d", i)
+	}
+	// other levels have their own allowance, and Panic is never
+	// limited
+	r.Info("info")
+	for i := 0; i < 3; i++ {
+		r.Panic("panic %d", i)
+	}
+	c.Advance(time.Second)
+	r.Warning("after")
+
+	got := levelMessages(tl)
+	want := []string{
+		"WARN flood 0",
+		"WARN flood 1",
+		"INFO info",
+		"PANIC panic 0",
+		"PANIC panic 1",
+		"PANIC panic 2",
+		"WARN 3 messages suppressed",
+		"WARN after",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	st := r.Stats()
+	if st.Written[WarningLevel] != 3 || st.Dropped[WarningLevel] != 3 || st.Written[PanicLevel] != 3 {
+		t.Errorf("got stats %+v", st)
+	}
+}
+
+func TestRateLimitedWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		advance time.Duration
+		want    int
+	}{
+		{"same window", 999 * time.Millisecond, 1},
+		{"next window", time.Second, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl := NewTest()
+			r, c, _ := newTestRateLimited(t, tl, 1, time.Second)
+			r.Info("one")
+			c.Advance(tt.advance)
+			r.Info("two")
+			if n := len(tl.Messages()); n != tt.want {
+				t.Errorf("forwarded %d lines, want %d", n, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitedSummaryOnTick(t *testing.T) {
+	tl := NewTest()
+	r, c, check := newTestRateLimited(t, tl, 1, time.Second)
+	pl := r.Prefix("p")
+	for i := 0; i < 3; i++ {
+		pl.Warning("flood %d", i)
+		r.Info("info %d", i)
+	}
+	// not over yet
+	c.Advance(999 * time.Millisecond)
+	check()
+	if got := levelMessages(tl); len(got) != 2 {
+		t.Fatalf("got %q before the period was over", got)
+	}
+	// written without waiting for another line, and only once
+	c.Advance(time.Millisecond)
+	check()
+	check()
+	want := []string{
+		"WARN flood 0",
+		"INFO info 0",
+		"INFO 2 messages suppressed",
+		"WARN 2 messages suppressed",
+	}
+	if got := levelMessages(tl); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// the summary is written to the root logger
+	if e := tl.Messages()[3]; e.Prefix != "" {
+		t.Errorf("got summary with prefix %q", e.Prefix)
+	}
+}
+
+func TestRateLimitedFlush(t *testing.T) {
+	tl := NewTest()
+	r, c, _ := newTestRateLimited(t, tl, 1, time.Second)
+	for i := 0; i < 3; i++ {
+		r.Warning("flood %d", i)
+	}
+	r.Flush()
+	// the allowance stays used up for the rest of the period
+	r.Warning("flood 3")
+	c.Advance(time.Second)
+	r.Warning("after")
+	want := []string{
+		"WARN flood 0",
+		"WARN 2 messages suppressed",
+		"WARN 1 messages suppressed",
+		"WARN after",
+	}
+	if got := levelMessages(tl); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRateLimitedClose(t *testing.T) {
+	tl := NewTest()
+	r := RateLimited(tl, 1, time.Hour)
+	r.Info("one")
+	r.Info("two")
+	r.Info("three")
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"INFO one", "INFO 2 messages suppressed"}
+	if got := levelMessages(tl); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// closing again reports nothing new
+	r.Close()
+	if n := len(tl.Messages()); n != 2 {
+		t.Errorf("got %d entries after a second Close, want 2", n)
+	}
+}
@@ -0,0 +1,43 @@
+package log
+
+import "fmt"
+import "runtime"
+
+// Recover is meant to be deferred, as in defer l.Recover(). If the
+// function panics, Recover logs the panic value and the goroutine's
+// stack at PanicLevel, and then panics again with the same value.
+func (l *DefaultLogger) Recover() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if l.enabled(PanicLevel) {
+		l.out(PanicLevel, recovered(r))
+		l.sync()
+	}
+	panic(r)
+}
+
+// RecoverAndContinue is meant to be deferred, as in
+// defer l.RecoverAndContinue(). If the function panics,
+// RecoverAndContinue logs the panic value and the goroutine's stack
+// at ErrorLevel, and swallows the panic so that the deferring
+// function returns normally.
+func (l *DefaultLogger) RecoverAndContinue() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if l.enabled(ErrorLevel) {
+		l.out(ErrorLevel, recovered(r))
+	}
+}
+
+// recovered describes a recovered panic value along with the stack
+// of the panicking goroutine, which is still intact while deferred
+// functions run.
+func recovered(r interface{}) string {
+	buf := make([]byte, 64<<10)
+	buf = buf[:runtime.Stack(buf, false)]
+	return fmt.Sprintf("recovered: %v\n%s", r, buf)
+}
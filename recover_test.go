@@ -0,0 +1,61 @@
+package log
+
+import "testing"
+import "strings"
+
+// panicky panics with v, recovering with recoverer.
+func panicky(recoverer func(), v interface{}) {
+	defer recoverer()
+	if v != nil {
+		panic(v)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name    string
+		recover func(l *DefaultLogger) func()
+		value   interface{}
+		repanic bool
+		tag     string
+		want    string
+	}{
+		{"Recover", func(l *DefaultLogger) func() { return l.Recover }, "boom", true, "PANIC", "boom"},
+		{"RecoverAndContinue", func(l *DefaultLogger) func() { return l.RecoverAndContinue }, "boom", false, "ERROR", "boom"},
+		{"non-string value", func(l *DefaultLogger) func() { return l.Recover }, 42, true, "PANIC", "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			v := catchPanic(func() { panicky(tt.recover(l), tt.value) })
+			if tt.repanic && v != tt.value {
+				t.Errorf("panicked with %v, want %v again", v, tt.value)
+			}
+			if !tt.repanic && v != nil {
+				t.Errorf("panicked with %v, want the panic swallowed", v)
+			}
+			got := buf.String()
+			if !strings.HasPrefix(got, "2006-01-02T15:04:05Z\t"+tt.tag+"\t\trecovered: ") ||
+				!strings.Contains(got, "recovered: "+tt.want+"\n") {
+				t.Errorf("got %q, want the recovered value at %s", got, tt.tag)
+			}
+			// the stack is that of the panic, not of the recovery
+			if !strings.Contains(got, "goroutine ") || !strings.Contains(got, "log.panicky(") {
+				t.Errorf("got %q, want the stack of panicky", got)
+			}
+		})
+	}
+}
+
+func TestRecoverNoPanic(t *testing.T) {
+	for _, recoverer := range []string{"Recover", "RecoverAndContinue"} {
+		l, buf := newLogger()
+		f := l.Recover
+		if recoverer == "RecoverAndContinue" {
+			f = l.RecoverAndContinue
+		}
+		if v := catchPanic(func() { panicky(f, nil) }); v != nil || buf.String() != "" {
+			t.Errorf("%s: panicked with %v and wrote %q, want neither", recoverer, v, buf.String())
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package log
+
+import "fmt"
+import "sync"
+
+// A RingLogger keeps the most recent lines logged to it in memory,
+// formatted as DefaultLogger text lines, such as for a /debug/log
+// endpoint to show. It can also forward every line to another
+// Logger, so that the lines are both kept and written as usual. It is
+// safe for concurrent logging and reading.
+type RingLogger struct {
+	d    *DefaultLogger
+	next Logger
+	ring *ring
+}
+
+// ring is the store shared by a tree of RingLoggers, holding up to
+// len(lines) lines starting at start.
+type ring struct {
+	mu    sync.Mutex
+	lines []string
+	start int
+	n     int
+}
+
+// Ring returns a logger that keeps the last capacity lines at or
+// above its threshold, which is InfoLevel unless changed with
+// SetLevel, and forwards every line to next. A nil next forwards to
+// a NullLogger, so Panic still panics and Fatal still exits. Loggers
+// derived via Prefix and With share the store.
+func Ring(capacity int, next Logger) *RingLogger {
+	if capacity < 0 {
+		capacity = 0
+	}
+	rb := &ring{lines: make([]string, capacity)}
+	d := NewWriter(rb)
+	d.SetLineTerminator("")
+	return &RingLogger{d: d, next: OrNull(next), ring: rb}
+}
+
+// Write stores p as a line, evicting the oldest if the ring is full.
+func (rb *ring) Write(p []byte) (int, error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if len(rb.lines) == 0 {
+		return len(p), nil
+	}
+	rb.lines[(rb.start+rb.n)%len(rb.lines)] = string(p)
+	if rb.n < len(rb.lines) {
+		rb.n++
+	} else {
+		rb.start = (rb.start + 1) % len(rb.lines)
+	}
+	return len(p), nil
+}
+
+// Lines returns a copy of the lines kept so far, oldest first.
+func (r *RingLogger) Lines() []string {
+	rb := r.ring
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	lines := make([]string, rb.n)
+	for i := range lines {
+		lines[i] = rb.lines[(rb.start+i)%len(rb.lines)]
+	}
+	return lines
+}
+
+// SetLevel sets the minimum level of the lines kept, for r and every
+// logger sharing its store. It doesn't affect what is forwarded.
+func (r *RingLogger) SetLevel(level Level) {
+	r.d.SetLevel(level)
+}
+
+// Log keeps the line if it is at or above the threshold, and then
+// forwards it to the next logger, which panics at PanicLevel and
+// exits at FatalLevel. Those levels are always forwarded, even if
+// neither r nor the next logger is enabled for them, so that the
+// panic or exit still happens.
+func (r *RingLogger) Log(level Level, format string, v ...interface{}) {
+	if level < PanicLevel && !r.Enabled(level) {
+		return
+	}
+	t := fmt.Sprintf(format, v...)
+	if r.d.enabled(level) {
+		r.d.out(level, t)
+	}
+	r.next.Log(level, "%s", t)
+}
+
+// Debug keeps the line, if enabled, and forwards it.
+func (r *RingLogger) Debug(format string, v ...interface{}) {
+	r.Log(DebugLevel, format, v...)
+}
+
+// Info keeps the line and forwards it.
+func (r *RingLogger) Info(format string, v ...interface{}) {
+	r.Log(InfoLevel, format, v...)
+}
+
+// Warning keeps the line and forwards it.
+func (r *RingLogger) Warning(format string, v ...interface{}) {
+	r.Log(WarningLevel, format, v...)
+}
+
+// Error keeps the line and forwards it.
+func (r *RingLogger) Error(format string, v ...interface{}) {
+	r.Log(ErrorLevel, format, v...)
+}
+
+// Panic keeps the line, and then forwards it to the next logger,
+// which panics.
+func (r *RingLogger) Panic(format string, v ...interface{}) {
+	r.Log(PanicLevel, format, v...)
+}
+
+// Fatal keeps the line, and then forwards it to the next logger,
+// which exits.
+func (r *RingLogger) Fatal(format string, v ...interface{}) {
+	r.Log(FatalLevel, format, v...)
+}
+
+// Enabled reports whether r would keep a line at level, or the next
+// logger would write it.
+func (r *RingLogger) Enabled(level Level) bool {
+	return r.d.enabled(level) || r.next.Enabled(level)
+}
+
+// Must calls r.Panic() if err is not nil, and otherwise forwards to
+// the next logger.
+func (r *RingLogger) Must(message string, err error) {
+	if err != nil {
+		r.Panic("Failed to %s: %v", message, err)
+	}
+	r.next.Must(message, nil)
+}
+
+// Prefix returns a ring logger that adds prefix to the lines it
+// keeps and forwards, sharing r's store.
+func (r *RingLogger) Prefix(prefix string) Logger {
+	return &RingLogger{
+		d:    r.d.Prefix(prefix).(*DefaultLogger),
+		next: r.next.Prefix(prefix),
+		ring: r.ring,
+	}
+}
+
+// With returns a ring logger that adds key=value to the lines it
+// keeps and forwards, sharing r's store.
+func (r *RingLogger) With(key string, value interface{}) Logger {
+	return &RingLogger{
+		d:    r.d.With(key, value).(*DefaultLogger),
+		next: r.next.With(key, value),
+		ring: r.ring,
+	}
+}
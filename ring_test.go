@@ -0,0 +1,106 @@
+package log
+
+import "fmt"
+import "sync"
+import "testing"
+import "strings"
+
+func TestRing(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		lines    int
+		want     []string
+	}{
+		{"empty", 3, 0, []string{}},
+		{"under capacity", 3, 2, []string{"0", "1"}},
+		{"full", 3, 3, []string{"0", "1", "2"}},
+		{"overflowed", 3, 7, []string{"4", "5", "6"}},
+		{"zero capacity", 0, 2, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Ring(tt.capacity, nil)
+			r.d.SetClock(FixedClock(testTime))
+			pl := r.Prefix("p")
+			for i := 0; i < tt.lines; i++ {
+				pl.Info("%d", i)
+			}
+			var want []string
+			for _, msg := range tt.want {
+				want = append(want, "2006-01-02T15:04:05Z\tINFO\tp\t"+msg)
+			}
+			if got := r.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") || len(got) != len(want) {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestRingForwards(t *testing.T) {
+	tl := NewTest()
+	r := Ring(2, tl)
+	r.SetLevel(WarningLevel)
+	r.Info("forwarded only")
+	r.Warning("kept and forwarded")
+	if n := len(r.Lines()); n != 1 {
+		t.Errorf("kept %d lines, want 1", n)
+	}
+	if n := len(tl.Messages()); n != 2 {
+		t.Errorf("forwarded %d lines, want 2", n)
+	}
+}
+
+func TestRingPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		next func() Logger
+	}{
+		{"nil next", func() Logger { return nil }},
+		// the next logger isn't enabled at PanicLevel, and neither is
+		// the ring, but Panic must still panic
+		{"disabled next", func() Logger {
+			l, _ := newLogger()
+			l.SetLevel(FatalLevel)
+			return l
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Ring(2, tt.next())
+			r.SetLevel(FatalLevel)
+			if v := catchPanic(func() { r.Panic("boom") }); v != "boom" {
+				t.Errorf("panicked with %v, want boom", v)
+			}
+			code := stubExit(t)
+			r.Fatal("the end")
+			if *code != 1 {
+				t.Errorf("exited with %d, want 1", *code)
+			}
+		})
+	}
+}
+
+func TestRingConcurrent(t *testing.T) {
+	r := Ring(10, nil)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(2)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				r.Info("%s", fmt.Sprint(g, i))
+			}
+		}(g)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				r.Lines()
+			}
+		}()
+	}
+	wg.Wait()
+	if n := len(r.Lines()); n != 10 {
+		t.Errorf("kept %d lines, want 10", n)
+	}
+}
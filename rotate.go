@@ -0,0 +1,167 @@
+package log
+
+import "os"
+import "fmt"
+import "sync"
+
+// A RotatingWriter writes to a file, and when a write would take the
+// file past a size limit, renames it with a numeric suffix and
+// starts a fresh one. With a path of "app.log", the previous files
+// are "app.log.1" (the newest), "app.log.2" and so on. It is safe
+// for concurrent use, so several loggers may share one.
+type RotatingWriter struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	maxFiles int
+	f        *os.File
+	size     int64
+	header   func() []byte
+	rotErr   error
+}
+
+// NewRotatingWriter opens path for appending, creating it if need
+// be, and returns a writer that rotates it once it reaches maxBytes,
+// keeping at most maxFiles previous files.
+func NewRotatingWriter(path string, maxBytes int64, maxFiles int) (*RotatingWriter, error) {
+	r := &RotatingWriter{
+		path:     path,
+		maxBytes: maxBytes,
+		maxFiles: maxFiles,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetRotateHeader makes r write what fn returns at the start of
+// each fresh file it rotates to, before the write that made it
+// rotate, so that every file can describe itself. DefaultLogger's
+// SetHeader sets it for a RotatingWriter it writes to. A nil fn
+// removes it.
+func (r *RotatingWriter) SetRotateHeader(fn func() []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.header = fn
+}
+
+// open opens the current file, and notes how big it already is.
+func (r *RotatingWriter) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f = f
+	r.size = fi.Size()
+	return nil
+}
+
+// LastRotateError returns the error from the last rotation, or nil
+// if it worked or none was needed yet. Write doesn't report a failed
+// rotation once p has been written, so this is where to look.
+func (r *RotatingWriter) LastRotateError() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rotErr
+}
+
+// Write writes p to the current file, rotating first if p would take
+// it past the size limit. A single write is never split across
+// files, so a line larger than the limit gets a file to itself. If
+// rotating fails, p is still written, to the file that couldn't be
+// moved aside, and Write succeeds; the error is kept for
+// LastRotateError, and the next write tries again. Only if no file
+// could be opened at all is the rotation error returned.
+func (r *RotatingWriter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	if r.size > 0 && r.size+int64(len(p)) > r.maxBytes {
+		rerr := r.rotate()
+		r.rotErr = rerr
+		if r.f == nil {
+			return 0, rerr
+		}
+		if rerr == nil && r.header != nil {
+			// a failed rotation leaves the old file, which already
+			// starts with the header
+			n, _ := r.f.Write(r.header())
+			r.size += int64(n)
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts each previous file up by one, dropping the oldest,
+// and moves the current file to suffix 1. Whether or not that
+// works, it then opens the file at r.path, so that writing can go on,
+// and it returns the first error. The caller must hold r.mu.
+func (r *RotatingWriter) rotate() error {
+	err := r.f.Close()
+	r.f = nil
+	if err == nil {
+		err = r.shift()
+	}
+	if oerr := r.open(); err == nil {
+		err = oerr
+	}
+	return err
+}
+
+// shift renames the closed current and previous files up by one.
+func (r *RotatingWriter) shift() error {
+	if r.maxFiles < 1 {
+		return os.Remove(r.path)
+	}
+	os.Remove(r.name(r.maxFiles))
+	for i := r.maxFiles - 1; i >= 1; i-- {
+		// the older files may not all exist yet
+		os.Rename(r.name(i), r.name(i+1))
+	}
+	return os.Rename(r.path, r.name(1))
+}
+
+// name returns the path of the nth previous file.
+func (r *RotatingWriter) name(n int) string {
+	return fmt.Sprintf("%s.%d", r.path, n)
+}
+
+// hasData reports whether the current file has anything in it.
+func (r *RotatingWriter) hasData() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.size > 0
+}
+
+// Sync flushes the current file to storage.
+func (r *RotatingWriter) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return os.ErrClosed
+	}
+	return r.f.Sync()
+}
+
+// Close closes the current file. Later writes fail.
+func (r *RotatingWriter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return os.ErrClosed
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
@@ -0,0 +1,152 @@
+package log
+
+import "os"
+import "testing"
+import "strings"
+import "path/filepath"
+
+// readFile returns the contents of path, or "" if it doesn't exist.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRotatingWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxFiles int
+		writes   []string
+		want     []string // the current file, then .1, .2 ...
+	}{
+		{"under the limit", 2, []string{"abc\n", "def\n"}, []string{"abc\ndef\n", ""}},
+		{"one rotation", 2, []string{"abcd\n", "efgh\n"}, []string{"efgh\n", "abcd\n", ""}},
+		{"oversized line", 2, []string{"abcdefghijkl\n", "m\n"}, []string{"m\n", "abcdefghijkl\n"}},
+		{
+			"oldest dropped", 2,
+			[]string{"1111\n", "2222\n", "3333\n", "4444\n"},
+			[]string{"4444\n", "3333\n", "2222\n", ""},
+		},
+		{"no previous files", 0, []string{"1111\n", "2222\n"}, []string{"2222\n", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			r, err := NewRotatingWriter(path, 8, tt.maxFiles)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			for _, s := range tt.writes {
+				if _, err := r.Write([]byte(s)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i, want := range tt.want {
+				name := path
+				if i > 0 {
+					name = r.name(i)
+				}
+				if got := readFile(t, name); got != want {
+					t.Errorf("%s = %q, want %q", filepath.Base(name), got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRotatingWriterAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("abcdef\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRotatingWriter(path, 8, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	// the existing contents count towards the limit
+	r.Write([]byte("gh\n"))
+	if got := readFile(t, r.name(1)); got != "abcdef\n" {
+		t.Errorf("app.log.1 = %q, want the existing contents", got)
+	}
+}
+
+func TestRotatingWriterRotateFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	r, err := NewRotatingWriter(path, 8, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	// a non-empty directory in the way of app.log.1 can be neither
+	// removed nor replaced
+	if err := os.MkdirAll(filepath.Join(r.name(1), "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Write([]byte("1111\n"))
+	// the line is written all the same, and the error kept aside
+	if n, err := r.Write([]byte("2222\n")); err != nil || n != 5 {
+		t.Errorf("Write = %d, %v, want 5 and no error", n, err)
+	}
+	if r.LastRotateError() == nil {
+		t.Error("LastRotateError = nil, want the rename error")
+	}
+	os.RemoveAll(r.name(1))
+	// the next write rotates after all
+	if _, err := r.Write([]byte("3333\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.LastRotateError(); err != nil {
+		t.Errorf("LastRotateError = %v after rotating, want nil", err)
+	}
+	if got := readFile(t, r.name(1)); got != "1111\n2222\n" {
+		t.Errorf("app.log.1 = %q", got)
+	}
+	if got := readFile(t, path); got != "3333\n" {
+		t.Errorf("app.log = %q", got)
+	}
+}
+
+func TestRotatingWriterRotateFailsKeepsLogging(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	r, err := NewRotatingWriter(path, 8, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := os.MkdirAll(filepath.Join(r.name(1), "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	l := NewWriter(r)
+	l.SetClock(FixedClock(testTime))
+	// a logger without an error handler would panic on a write error
+	if v := catchPanic(func() {
+		for i := 0; i < 3; i++ {
+			l.Info("line %d", i)
+		}
+	}); v != nil {
+		t.Fatalf("panicked with %v", v)
+	}
+	if got := readFile(t, path); strings.Count(got, "\n") != 3 {
+		t.Errorf("app.log = %q, want all three lines", got)
+	}
+	if r.LastRotateError() == nil {
+		t.Error("LastRotateError = nil, want the rename error")
+	}
+}
+
+func TestRotatingWriterClosed(t *testing.T) {
+	r, err := NewRotatingWriter(filepath.Join(t.TempDir(), "app.log"), 8, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if _, err := r.Write([]byte("x\n")); err != os.ErrClosed {
+		t.Errorf("Write after Close = %v, want os.ErrClosed", err)
+	}
+}
@@ -0,0 +1,138 @@
+package log
+
+import "sync/atomic"
+
+// A SampledLogger forwards only a sample of the lines at each level
+// to another Logger. Panic and Fatal are never sampled out.
+type SampledLogger struct {
+	l  Logger
+	st *sampleState
+}
+
+// sampleState counts the calls at each level for a tree of
+// SampledLoggers.
+type sampleState struct {
+	first, every uint64
+
+	trace, debug, info, warning, error uint64
+
+	stats *stats
+}
+
+// Sampled returns a logger that forwards every nth call at each
+// level to l (the nth, the 2nth and so on) and drops the rest.
+// Loggers derived via Prefix and With share the counts. It is safe
+// for concurrent use if l is.
+func Sampled(l Logger, n int) *SampledLogger {
+	return SampledAfter(l, 0, n)
+}
+
+// SampledAfter returns a logger that forwards the first calls at
+// each level to l, and after that every nth call.
+func SampledAfter(l Logger, first, n int) *SampledLogger {
+	if n < 1 {
+		n = 1
+	}
+	return &SampledLogger{
+		l: l,
+		st: &sampleState{
+			first: uint64(first),
+			every: uint64(n),
+			stats: &stats{},
+		},
+	}
+}
+
+// Stats returns the number of lines forwarded and dropped so far by
+// s and the loggers derived from it.
+func (s *SampledLogger) Stats() Stats {
+	return s.st.stats.snapshot()
+}
+
+// keep counts a call using counter, and reports whether it should be
+// forwarded.
+func (st *sampleState) keep(counter *uint64) bool {
+	c := atomic.AddUint64(counter, 1)
+	return c <= st.first || (c-st.first)%st.every == 0
+}
+
+// counter returns the count for level, or nil if level is never
+// sampled out.
+func (st *sampleState) counter(level Level) *uint64 {
+	switch {
+	case level >= PanicLevel:
+		return nil
+	case level >= ErrorLevel:
+		return &st.error
+	case level >= WarningLevel:
+		return &st.warning
+	case level >= InfoLevel:
+		return &st.info
+	case level >= DebugLevel:
+		return &st.debug
+	}
+	return &st.trace
+}
+
+// Log forwards to the wrapped logger if sampled. PanicLevel and
+// above are always forwarded.
+func (s *SampledLogger) Log(level Level, format string, v ...interface{}) {
+	c := s.st.counter(level)
+	ok := c == nil || s.st.keep(c)
+	s.st.stats.count(level, ok)
+	if ok {
+		s.l.Log(level, format, v...)
+	}
+}
+
+// Debug forwards to the wrapped logger if sampled.
+func (s *SampledLogger) Debug(format string, v ...interface{}) {
+	s.Log(DebugLevel, format, v...)
+}
+
+// Info forwards to the wrapped logger if sampled.
+func (s *SampledLogger) Info(format string, v ...interface{}) {
+	s.Log(InfoLevel, format, v...)
+}
+
+// Warning forwards to the wrapped logger if sampled.
+func (s *SampledLogger) Warning(format string, v ...interface{}) {
+	s.Log(WarningLevel, format, v...)
+}
+
+// Error forwards to the wrapped logger if sampled.
+func (s *SampledLogger) Error(format string, v ...interface{}) {
+	s.Log(ErrorLevel, format, v...)
+}
+
+// Panic always forwards to the wrapped logger.
+func (s *SampledLogger) Panic(format string, v ...interface{}) {
+	s.Log(PanicLevel, format, v...)
+}
+
+// Fatal always forwards to the wrapped logger.
+func (s *SampledLogger) Fatal(format string, v ...interface{}) {
+	s.Log(FatalLevel, format, v...)
+}
+
+// Enabled reports whether the wrapped logger is enabled for level.
+func (s *SampledLogger) Enabled(level Level) bool {
+	return s.l.Enabled(level)
+}
+
+// Must forwards to the wrapped logger.
+func (s *SampledLogger) Must(message string, err error) {
+	s.l.Must(message, err)
+}
+
+// Prefix returns a sampled logger around the wrapped logger's
+// Prefix, sharing s's counts.
+func (s *SampledLogger) Prefix(prefix string) Logger {
+	return &SampledLogger{l: s.l.Prefix(prefix), st: s.st}
+}
+
+// With returns a sampled logger around the wrapped logger's With,
+// sharing s's counts.
+func (s *SampledLogger) With(key string, value interface{}) Logger {
+	return &SampledLogger{l: s.l.With(key, value), st: s.st}
+}
@@ -0,0 +1,80 @@
+package log
+
+import "fmt"
+import "sync"
+import "testing"
+
+func TestSampled(t *testing.T) {
+	tests := []struct {
+		name     string
+		first, n int
+		want     []int // the calls forwarded, counting from 1
+	}{
+		{"every third", 0, 3, []int{3, 6, 9}},
+		{"every call", 0, 1, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{"zero means every call", 0, 0, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{"first then sample", 3, 4, []int{1, 2, 3, 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl := NewTest()
+			s := SampledAfter(tl, tt.first, tt.n)
+			// derived loggers share the count
+			pl := s.Prefix("p")
+			for i := 1; i <= 10; i++ {
+				if i%2 == 0 {
+					pl.Info("%d", i)
+				} else {
+					s.Info("%d", i)
+				}
+				// another level doesn't use up the sample
+				s.Debug("debug")
+			}
+			var got []int
+			for _, e := range tl.Messages() {
+				if e.Level == InfoLevel {
+					got = append(got, e.Args[0].(int))
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("forwarded %v, want %v", got, tt.want)
+			}
+			if st := s.Stats(); st.Written[InfoLevel] != uint64(len(tt.want)) ||
+				st.Dropped[InfoLevel] != uint64(10-len(tt.want)) {
+				t.Errorf("got stats %+v", st)
+			}
+		})
+	}
+}
+
+func TestSampledNeverDropsPanic(t *testing.T) {
+	tl := NewTest()
+	tl.SetPanic(false)
+	s := Sampled(tl, 100)
+	for i := 0; i < 5; i++ {
+		s.Panic("boom")
+	}
+	if n := len(tl.Messages()); n != 5 {
+		t.Errorf("forwarded %d panics, want 5", n)
+	}
+}
+
+func TestSampledConcurrent(t *testing.T) {
+	const goroutines, lines = 8, 100
+	tl := NewTest()
+	s := Sampled(tl, 10)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				s.Info("hello")
+			}
+		}()
+	}
+	wg.Wait()
+	if n := len(tl.Messages()); n != goroutines*lines/10 {
+		t.Errorf("forwarded %d lines, want %d", n, goroutines*lines/10)
+	}
+}
@@ -0,0 +1,95 @@
+//go:build go1.21
+
+package log
+
+import "context"
+import "log/slog"
+
+// slogHandler is a slog.Handler that writes through a Logger.
+type slogHandler struct {
+	l     Logger
+	group string
+}
+
+// NewSlogHandler returns a slog.Handler that writes each record to l,
+// so that code using log/slog can share l's sinks:
+//
+//	slog.SetDefault(slog.New(NewSlogHandler(l)))
+//
+// Debug, Info, Warn and Error records are written at the matching
+// Level, records below LevelDebug at TraceLevel, and records above
+// LevelError at PanicLevel, so beware that those panic. Attributes
+// become fields added with With, and groups qualify the keys of the
+// attributes in them, as in "http.method".
+func NewSlogHandler(l Logger) slog.Handler {
+	return &slogHandler{l: l}
+}
+
+// fromSlogLevel maps a slog level onto the nearest Level at or
+// below it.
+func fromSlogLevel(lv slog.Level) Level {
+	switch {
+	case lv > slog.LevelError:
+		return PanicLevel
+	case lv >= slog.LevelError:
+		return ErrorLevel
+	case lv >= slog.LevelWarn:
+		return WarningLevel
+	case lv >= slog.LevelInfo:
+		return InfoLevel
+	case lv >= slog.LevelDebug:
+		return DebugLevel
+	}
+	return TraceLevel
+}
+
+// Enabled reports whether the Logger is enabled for level.
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.l.Enabled(fromSlogLevel(level))
+}
+
+// Handle writes r to the Logger, with its attributes as fields.
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	l := h.l
+	r.Attrs(func(a slog.Attr) bool {
+		l = withAttr(l, h.group, a)
+		return true
+	})
+	l.Log(fromSlogLevel(r.Level), "%s", r.Message)
+	return nil
+}
+
+// WithAttrs returns a handler whose Logger carries attrs as fields.
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	l := h.l
+	for _, a := range attrs {
+		l = withAttr(l, h.group, a)
+	}
+	return &slogHandler{l: l, group: h.group}
+}
+
+// WithGroup returns a handler that qualifies later attribute keys
+// with name.
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{l: h.l, group: qualify(h.group, name)}
+}
+
+// withAttr adds a to l as a field, flattening groups into dotted
+// keys.
+func withAttr(l Logger, group string, a slog.Attr) Logger {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		g := qualify(group, a.Key)
+		for _, ga := range v.Group() {
+			l = withAttr(l, g, ga)
+		}
+		return l
+	}
+	if a.Key == "" {
+		return l
+	}
+	return l.With(qualify(group, a.Key), v.Any())
+}
@@ -0,0 +1,86 @@
+//go:build go1.21
+
+package log
+
+import "context"
+import "testing"
+import "strings"
+import "log/slog"
+
+func TestSlogHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(s *slog.Logger)
+		want string
+	}{
+		{"debug", func(s *slog.Logger) { s.Debug("hello") }, "DEBUG\t\thello"},
+		{"info", func(s *slog.Logger) { s.Info("hello", "n", 3) }, "INFO\t\thello\tn=3"},
+		{"warn", func(s *slog.Logger) { s.Warn("hello") }, "WARN\t\thello"},
+		{"error", func(s *slog.Logger) { s.Error("hello") }, "ERROR\t\thello"},
+		{"trace", func(s *slog.Logger) { s.Log(context.Background(), slog.LevelDebug-4, "hello") }, "TRACE\t\thello"},
+		{"between levels", func(s *slog.Logger) { s.Log(context.Background(), slog.LevelInfo+2, "hello") }, "INFO\t\thello"},
+		{"with", func(s *slog.Logger) { s.With("a", 1).Info("hello", "b", 2) }, "INFO\t\thello\ta=1\tb=2"},
+		{"group", func(s *slog.Logger) {
+			s.WithGroup("http").Info("hello", "method", "GET")
+		}, "INFO\t\thello\thttp.method=GET"},
+		{"nested groups", func(s *slog.Logger) {
+			s.WithGroup("http").With("id", 7).WithGroup("req").Info("hello", "method", "GET")
+		}, "INFO\t\thello\thttp.id=7\thttp.req.method=GET"},
+		{"group attr", func(s *slog.Logger) {
+			s.Info("hello", slog.Group("user", "name", "ann", "id", 1))
+		}, "INFO\t\thello\tuser.name=ann\tuser.id=1"},
+		{"empty group name", func(s *slog.Logger) { s.WithGroup("").Info("hello", "k", 1) }, "INFO\t\thello\tk=1"},
+		{"empty key", func(s *slog.Logger) { s.Info("hello", slog.Attr{}) }, "INFO\t\thello"},
+		{"valuer", func(s *slog.Logger) { s.Info("hello", "v", testValuer{}) }, "INFO\t\thello\tv=resolved"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			l.SetLevel(TraceLevel)
+			tt.log(slog.New(NewSlogHandler(l)))
+			want := "2006-01-02T15:04:05Z\t" + tt.want
+			if got := strings.Join(buf.Lines(), "\n"); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// testValuer is a slog.LogValuer resolving to "resolved".
+type testValuer struct{}
+
+func (testValuer) LogValue() slog.Value {
+	return slog.StringValue("resolved")
+}
+
+func TestSlogHandlerThreshold(t *testing.T) {
+	l, buf := newLogger()
+	l.SetLevel(WarningLevel)
+	s := slog.New(NewSlogHandler(l))
+	if s.Enabled(context.Background(), slog.LevelInfo) || !s.Enabled(context.Background(), slog.LevelWarn) {
+		t.Error("Enabled doesn't follow the threshold")
+	}
+	s.Info("dropped")
+	if buf.String() != "" {
+		t.Errorf("got %q, want nothing", buf.String())
+	}
+}
+
+func TestSlogHandlerPanics(t *testing.T) {
+	l, _ := newLogger()
+	s := slog.New(NewSlogHandler(l))
+	if v := catchPanic(func() { s.Log(context.Background(), slog.LevelError+4, "boom") }); v != "boom" {
+		t.Errorf("panicked with %v, want boom", v)
+	}
+}
+
+func TestSlogHandlerRedacts(t *testing.T) {
+	l, buf := newLogger()
+	l.SetRedactKeys("password")
+	s := slog.New(NewSlogHandler(l))
+	s.WithGroup("http").Info("login", "password", "hunter2", slog.Group("user", "name", "ann", "Password", "x"))
+	want := "2006-01-02T15:04:05Z\tINFO\t\tlogin\thttp.password=***\thttp.user.name=ann\thttp.user.Password=***\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,84 @@
+package log
+
+import "sync/atomic"
+
+// Stats reports how many lines a wrapper such as RateLimited has
+// passed on and how many it has dropped, at each level.
+type Stats struct {
+	Written map[Level]uint64
+	Dropped map[Level]uint64
+}
+
+// numLevels is the number of levels from TraceLevel to FatalLevel.
+const numLevels = int(FatalLevel-TraceLevel) + 1
+
+// stats counts written and dropped lines for a tree of wrappers.
+// Levels beyond either end are counted with the nearest level.
+type stats struct {
+	written [numLevels]uint64
+	dropped [numLevels]uint64
+}
+
+// levelIndex returns the index of level in the counter arrays.
+func levelIndex(level Level) int {
+	switch {
+	case level < TraceLevel:
+		level = TraceLevel
+	case level > FatalLevel:
+		level = FatalLevel
+	}
+	return int(level - TraceLevel)
+}
+
+// count counts a line at level as written if ok, and as dropped
+// otherwise.
+func (s *stats) count(level Level, ok bool) {
+	if ok {
+		atomic.AddUint64(&s.written[levelIndex(level)], 1)
+	} else {
+		atomic.AddUint64(&s.dropped[levelIndex(level)], 1)
+	}
+}
+
+// snapshot returns the current counts, leaving out levels with none.
+func (s *stats) snapshot() Stats {
+	st := Stats{
+		Written: make(map[Level]uint64),
+		Dropped: make(map[Level]uint64),
+	}
+	for i := 0; i < numLevels; i++ {
+		level := TraceLevel + Level(i)
+		if n := atomic.LoadUint64(&s.written[i]); n > 0 {
+			st.Written[level] = n
+		}
+		if n := atomic.LoadUint64(&s.dropped[i]); n > 0 {
+			st.Dropped[level] = n
+		}
+	}
+	return st
+}
+
+// SetCountingEnabled makes l count the lines it writes at each
+// level, for Counts to report, such as to a metrics endpoint. It is
+// off by default, costing nothing, and when on costs an atomic add
+// per line. Loggers derived from l afterwards via Prefix and With
+// count into the same counters. Disabling it stops the counting and
+// forgets the counts.
+func (l *DefaultLogger) SetCountingEnabled(enabled bool) {
+	if !enabled {
+		l.counts = nil
+	} else if l.counts == nil {
+		l.counts = &stats{}
+	}
+}
+
+// Counts returns the number of lines written at each level since
+// counting was enabled with SetCountingEnabled, by l and the loggers
+// sharing its counters, leaving out levels with none. Lines whose
+// write failed aren't counted. It returns nil if counting is off.
+func (l *DefaultLogger) Counts() map[Level]uint64 {
+	if l.counts == nil {
+		return nil
+	}
+	return l.counts.snapshot().Written
+}
@@ -0,0 +1,116 @@
+package log
+
+import "sync"
+import "time"
+import "errors"
+import "reflect"
+import "testing"
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		wrap    func(l Logger) interface{ Stats() Stats }
+		written uint64
+		dropped uint64
+	}{
+		{"RateLimited", func(l Logger) interface{ Stats() Stats } { return RateLimited(l, 3, time.Hour) }, 3, 7},
+		{"Sampled", func(l Logger) interface{ Stats() Stats } { return Sampled(l, 5) }, 2, 8},
+		{"Dedup", func(l Logger) interface{ Stats() Stats } { return Dedup(l, 0) }, 1, 9},
+		{"FilterPrefix", func(l Logger) interface{ Stats() Stats } {
+			return FilterPrefix(l, map[string]Level{"": ErrorLevel})
+		}, 0, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _ := newLogger()
+			w := tt.wrap(l)
+			// concurrently, and through a derived logger, which must
+			// count into the same counters
+			pl := w.(Logger).Prefix("p")
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					pl.Warning("hello")
+				}()
+			}
+			wg.Wait()
+			catchPanic(func() { pl.Panic("never dropped") })
+
+			st := w.Stats()
+			if st.Written[WarningLevel] != tt.written || st.Dropped[WarningLevel] != tt.dropped {
+				t.Errorf("warnings written %d dropped %d, want %d and %d",
+					st.Written[WarningLevel], st.Dropped[WarningLevel], tt.written, tt.dropped)
+			}
+			if st.Written[PanicLevel] != 1 || st.Dropped[PanicLevel] != 0 {
+				t.Errorf("got panic stats written %d dropped %d, want 1 and 0",
+					st.Written[PanicLevel], st.Dropped[PanicLevel])
+			}
+			// levels without lines are left out
+			if _, ok := st.Written[InfoLevel]; ok {
+				t.Errorf("got stats for InfoLevel: %+v", st)
+			}
+		})
+	}
+}
+
+func TestStatsClampsLevels(t *testing.T) {
+	s := &stats{}
+	s.count(TraceLevel-5, true)
+	s.count(FatalLevel+5, false)
+	st := s.snapshot()
+	if st.Written[TraceLevel] != 1 || st.Dropped[FatalLevel] != 1 {
+		t.Errorf("got %+v", st)
+	}
+}
+
+func TestCounts(t *testing.T) {
+	l, _ := newLogger()
+	if l.Counts() != nil {
+		t.Error("counting before it was enabled")
+	}
+	l.SetCountingEnabled(true)
+	pl := l.Prefix("p").With("k", 1)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(l Logger) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Info("hello")
+				if j%10 == 0 {
+					l.Error("failed")
+				}
+				// below the threshold, so not counted
+				l.Debug("hidden")
+			}
+		}([]Logger{l, pl}[i%2])
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Counts()
+			}
+		}()
+	}
+	wg.Wait()
+	want := map[Level]uint64{InfoLevel: 800, ErrorLevel: 80}
+	if got := pl.(*DefaultLogger).Counts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	l.SetCountingEnabled(false)
+	if l.Counts() != nil {
+		t.Error("still counting once disabled")
+	}
+}
+
+func TestCountsFailedWrites(t *testing.T) {
+	l := NewWriter(errWriter{errors.New("broken pipe")})
+	l.SetErrorHandler(func(error) {})
+	l.SetCountingEnabled(true)
+	l.Info("lost")
+	if got := l.Counts(); len(got) != 0 {
+		t.Errorf("got %v, want no counts", got)
+	}
+}
@@ -0,0 +1,137 @@
+//go:build !windows && !plan9
+
+package log
+
+import "fmt"
+import "log/syslog"
+
+var _ Logger = (*syslogLogger)(nil)
+
+// syslogLogger writes to the system log. Syslog has no notion of
+// nesting, so the prefix and fields are written into the message.
+type syslogLogger struct {
+	w      *syslog.Writer
+	prefix string
+	fields []field
+}
+
+// NewSyslog returns a Logger that writes to the local syslog daemon
+// with the given tag, mapping each level onto the matching syslog
+// priority. Panic and Fatal are written at LOG_CRIT.
+func NewSyslog(tag string) (Logger, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogLogger{w: w}, nil
+}
+
+// message renders the prefix, text and fields as one syslog message.
+func (s *syslogLogger) message(t string) string {
+	if s.prefix != "" {
+		t = s.prefix + ": " + t
+	}
+	return t + textFields(s.fields)
+}
+
+func (s *syslogLogger) out(write func(string) error, t string) {
+	if err := write(s.message(t)); err != nil {
+		panic(fmt.Sprintf("Failed to write log!\nError: %v\nLog: %s\n", err, t))
+	}
+}
+
+// Debug writes to syslog at LOG_DEBUG.
+func (s *syslogLogger) Debug(format string, v ...interface{}) {
+	s.out(s.w.Debug, fmt.Sprintf(format, v...))
+}
+
+// Info writes to syslog at LOG_INFO.
+func (s *syslogLogger) Info(format string, v ...interface{}) {
+	s.out(s.w.Info, fmt.Sprintf(format, v...))
+}
+
+// Warning writes to syslog at LOG_WARNING.
+func (s *syslogLogger) Warning(format string, v ...interface{}) {
+	s.out(s.w.Warning, fmt.Sprintf(format, v...))
+}
+
+// Error writes to syslog at LOG_ERR.
+func (s *syslogLogger) Error(format string, v ...interface{}) {
+	s.out(s.w.Err, fmt.Sprintf(format, v...))
+}
+
+// Panic writes to syslog at LOG_CRIT, and then panics.
+func (s *syslogLogger) Panic(format string, v ...interface{}) {
+	t := fmt.Sprintf(format, v...)
+	s.out(s.w.Crit, t)
+	panic(t)
+}
+
+// Fatal writes to syslog at LOG_CRIT, and then exits the process
+// with status 1.
+func (s *syslogLogger) Fatal(format string, v ...interface{}) {
+	s.fatal(fmt.Sprintf(format, v...))
+	osExit(1)
+}
+
+func (s *syslogLogger) fatal(t string) {
+	s.out(s.w.Crit, t)
+}
+
+// Log writes to syslog at the priority matching level, panicking
+// and exiting at PanicLevel and FatalLevel like Panic and Fatal.
+func (s *syslogLogger) Log(level Level, format string, v ...interface{}) {
+	switch {
+	case level >= FatalLevel:
+		s.Fatal(format, v...)
+	case level == PanicLevel:
+		s.Panic(format, v...)
+	case level >= ErrorLevel:
+		s.Error(format, v...)
+	case level >= WarningLevel:
+		s.Warning(format, v...)
+	case level >= InfoLevel:
+		s.Info(format, v...)
+	default:
+		// syslog has nothing below LOG_DEBUG for TraceLevel
+		s.Debug(format, v...)
+	}
+}
+
+// Enabled reports true, leaving any filtering to the syslog daemon.
+func (s *syslogLogger) Enabled(level Level) bool {
+	return true
+}
+
+// Must calls s.Panic() if err is not nil.
+func (s *syslogLogger) Must(message string, err error) {
+	if err != nil {
+		s.Panic("Failed to %s: %v", message, err)
+	}
+}
+
+// CurrentPrefix returns the prefix accumulated by Prefix calls so
+// far.
+func (s *syslogLogger) CurrentPrefix() string {
+	return s.prefix
+}
+
+// Prefix returns a new syslog logger with this prefix appended,
+// sharing the connection with s.
+func (s *syslogLogger) Prefix(prefix string) Logger {
+	ns := *s
+	if s.prefix == "" {
+		ns.prefix = prefix
+	} else {
+		ns.prefix = s.prefix + ":" + prefix
+	}
+	return &ns
+}
+
+// With returns a new syslog logger that adds key=value to each
+// message.
+func (s *syslogLogger) With(key string, value interface{}) Logger {
+	ns := *s
+	ns.fields = append(s.fields[:len(s.fields):len(s.fields)], field{key: key, value: value})
+	return &ns
+}
@@ -0,0 +1,81 @@
+//go:build !windows && !plan9
+
+package log
+
+import "net"
+import "time"
+import "strings"
+import "testing"
+import "log/syslog"
+
+// dialTestSyslog returns a syslog logger sending to a UDP socket
+// standing in for the daemon, and a function reading the next
+// message it receives.
+func dialTestSyslog(t *testing.T) (*syslogLogger, func() string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("can't listen on UDP: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	w, err := syslog.Dial("udp", conn.LocalAddr().String(), syslog.LOG_INFO|syslog.LOG_USER, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Close() })
+
+	read := func() string {
+		t.Helper()
+		buf := make([]byte, 2048)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(buf[:n])
+	}
+	return &syslogLogger{w: w}, read
+}
+
+func TestSyslogPriorities(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l Logger)
+		pri  string
+	}{
+		{"Debug", func(l Logger) { l.Debug("hello") }, "<15>"},
+		{"Info", func(l Logger) { l.Info("hello") }, "<14>"},
+		{"Warning", func(l Logger) { l.Warning("hello") }, "<12>"},
+		{"Error", func(l Logger) { l.Error("hello") }, "<11>"},
+		{"Panic", func(l Logger) { catchPanic(func() { l.Panic("hello") }) }, "<10>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, read := dialTestSyslog(t)
+			tt.log(l.Prefix("db").Prefix("query").With("rows", 3))
+			msg := read()
+			if !strings.HasPrefix(msg, tt.pri) {
+				t.Errorf("got %q, want priority %s", msg, tt.pri)
+			}
+			if want := "db:query: hello\trows=3"; !strings.Contains(msg, want) {
+				t.Errorf("got %q, want it to contain %q", msg, want)
+			}
+		})
+	}
+}
+
+func TestSyslogPanics(t *testing.T) {
+	l, read := dialTestSyslog(t)
+	if v := catchPanic(func() { l.Panic("boom %d", 1) }); v != "boom 1" {
+		t.Errorf("panicked with %v, want boom 1", v)
+	}
+	read()
+}
+
+func TestNewSyslog(t *testing.T) {
+	l, err := NewSyslog("log-test")
+	if err != nil {
+		t.Skipf("no local syslog daemon: %v", err)
+	}
+	l.Info("hello from the log package tests")
+}
@@ -0,0 +1,147 @@
+package log
+
+import "fmt"
+import "sync/atomic"
+
+// TB is the part of testing.TB that a TBLogger uses, so that this
+// package needn't import testing. *testing.T and *testing.B both
+// satisfy it.
+type TB interface {
+	Helper()
+	Log(args ...interface{})
+	Errorf(format string, args ...interface{})
+	Fatal(args ...interface{})
+}
+
+// A TBLogger writes each line through a test's Log method, so that
+// it shows up alongside the test's output, and only if the test
+// fails or runs verbosely. Loggers derived via Prefix and With write
+// to the same test.
+type TBLogger struct {
+	st     *tbState
+	prefix string
+	fields []field
+}
+
+// tbState is the test and options shared by a tree of TBLoggers.
+type tbState struct {
+	t    TB
+	fail int32
+}
+
+// NewTB returns a logger writing every line, at every level, to t.
+// Panic and Fatal write their line with t.Fatal, which stops the
+// test rather than panicking or exiting.
+func NewTB(t TB) *TBLogger {
+	return &TBLogger{st: &tbState{t: t}}
+}
+
+// SetFailOnWarning makes Warning and Error lines fail the test, by
+// writing them with t.Errorf, so that unexpected problems don't go
+// unnoticed. It is off by default. The setting is shared by every
+// logger derived from the same NewTB.
+func (l *TBLogger) SetFailOnWarning(enabled bool) {
+	var fail int32
+	if enabled {
+		fail = 1
+	}
+	atomic.StoreInt32(&l.st.fail, fail)
+}
+
+// Log writes the line to the test, failing or stopping it as the
+// level and settings call for.
+func (l *TBLogger) Log(level Level, format string, v ...interface{}) {
+	t := l.st.t
+	t.Helper()
+	line := string(textLine(entry{
+		level:  level,
+		prefix: l.prefix,
+		msg:    fmt.Sprintf(format, v...),
+		fields: l.fields,
+	}, false, nil))
+	switch {
+	case level >= PanicLevel:
+		t.Fatal(line)
+	case level >= WarningLevel && atomic.LoadInt32(&l.st.fail) != 0:
+		t.Errorf("%s", line)
+	default:
+		t.Log(line)
+	}
+}
+
+// Debug writes the line to the test.
+func (l *TBLogger) Debug(format string, v ...interface{}) {
+	l.st.t.Helper()
+	l.Log(DebugLevel, format, v...)
+}
+
+// Info writes the line to the test.
+func (l *TBLogger) Info(format string, v ...interface{}) {
+	l.st.t.Helper()
+	l.Log(InfoLevel, format, v...)
+}
+
+// Warning writes the line to the test, failing it if enabled with
+// SetFailOnWarning.
+func (l *TBLogger) Warning(format string, v ...interface{}) {
+	l.st.t.Helper()
+	l.Log(WarningLevel, format, v...)
+}
+
+// Error writes the line to the test, failing it if enabled with
+// SetFailOnWarning.
+func (l *TBLogger) Error(format string, v ...interface{}) {
+	l.st.t.Helper()
+	l.Log(ErrorLevel, format, v...)
+}
+
+// Panic writes the line with t.Fatal, stopping the test.
+func (l *TBLogger) Panic(format string, v ...interface{}) {
+	l.st.t.Helper()
+	l.Log(PanicLevel, format, v...)
+}
+
+// Fatal writes the line with t.Fatal, stopping the test.
+func (l *TBLogger) Fatal(format string, v ...interface{}) {
+	l.st.t.Helper()
+	l.Log(FatalLevel, format, v...)
+}
+
+// Enabled reports true, since l writes every line.
+func (l *TBLogger) Enabled(level Level) bool {
+	return true
+}
+
+// Must calls l.Panic() if err is not nil.
+func (l *TBLogger) Must(message string, err error) {
+	l.st.t.Helper()
+	if err != nil {
+		l.Panic("Failed to %s: %v", message, err)
+	}
+}
+
+// CurrentPrefix returns the prefix accumulated by Prefix calls so
+// far.
+func (l *TBLogger) CurrentPrefix() string {
+	return l.prefix
+}
+
+// Prefix returns a new TBLogger with this prefix appended, which
+// writes to the same test as l.
+func (l *TBLogger) Prefix(prefix string) Logger {
+	nl := *l
+	if l.prefix == "" {
+		nl.prefix = prefix
+	} else {
+		nl.prefix = l.prefix + ":" + prefix
+	}
+	return &nl
+}
+
+// With returns a new TBLogger that adds key=value to each line it
+// writes, and writes to the same test as l.
+func (l *TBLogger) With(key string, value interface{}) Logger {
+	nl := *l
+	nl.fields = append(l.fields[:len(l.fields):len(l.fields)], field{key: key, value: value})
+	return &nl
+}
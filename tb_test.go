@@ -0,0 +1,65 @@
+package log
+
+import "fmt"
+import "testing"
+
+// fakeTB records which of its methods are called, and with what.
+type fakeTB struct {
+	calls []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Log(args ...interface{}) {
+	f.calls = append(f.calls, "Log: "+fmt.Sprint(args...))
+}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.calls = append(f.calls, "Errorf: "+fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Fatal(args ...interface{}) {
+	f.calls = append(f.calls, "Fatal: "+fmt.Sprint(args...))
+}
+
+func TestTBLogger(t *testing.T) {
+	tests := []struct {
+		name   string
+		log    func(l Logger)
+		fail   bool
+		method string
+		tag    string
+	}{
+		{"Debug", func(l Logger) { l.Debug("hello") }, false, "Log", "DEBUG"},
+		{"Info", func(l Logger) { l.Info("hello") }, false, "Log", "INFO"},
+		{"Warning", func(l Logger) { l.Warning("hello") }, false, "Log", "WARN"},
+		{"Error", func(l Logger) { l.Error("hello") }, false, "Log", "ERROR"},
+		{"Info failing", func(l Logger) { l.Info("hello") }, true, "Log", "INFO"},
+		{"Warning failing", func(l Logger) { l.Warning("hello") }, true, "Errorf", "WARN"},
+		{"Error failing", func(l Logger) { l.Error("hello") }, true, "Errorf", "ERROR"},
+		{"Panic", func(l Logger) { l.Panic("hello") }, false, "Fatal", "PANIC"},
+		{"Fatal", func(l Logger) { l.Fatal("hello") }, false, "Fatal", "FATAL"},
+		{"Log", func(l Logger) { l.Log(TraceLevel, "hello") }, false, "Log", "TRACE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeTB{}
+			l := NewTB(fake)
+			l.SetFailOnWarning(tt.fail)
+			// Panic must not panic, nor Fatal exit
+			code := stubExit(t)
+			if v := catchPanic(func() { tt.log(l.Prefix("p").With("k", 1)) }); v != nil || *code != -1 {
+				t.Errorf("panicked with %v and exited with %d", v, *code)
+			}
+			want := tt.method + ": " + tt.tag + "\tp\thello\tk=1"
+			if len(fake.calls) != 1 || fake.calls[0] != want {
+				t.Errorf("got %q, want %q", fake.calls, want)
+			}
+		})
+	}
+}
+
+func TestTBLoggerRealT(t *testing.T) {
+	var l Logger = NewTB(t)
+	l.Info("logged through t.Log")
+}
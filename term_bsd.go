@@ -0,0 +1,15 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package log
+
+import "os"
+import "syscall"
+import "unsafe"
+
+// isTerminalFd reports whether fd is a terminal, by asking for its
+// terminal attributes.
+func isTerminalFd(f *os.File, fd uintptr) bool {
+	var t syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCGETA, uintptr(unsafe.Pointer(&t)))
+	return errno == 0
+}
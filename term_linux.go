@@ -0,0 +1,13 @@
+package log
+
+import "os"
+import "syscall"
+import "unsafe"
+
+// isTerminalFd reports whether fd is a terminal, by asking for its
+// terminal attributes.
+func isTerminalFd(f *os.File, fd uintptr) bool {
+	var t syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(&t)))
+	return errno == 0
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package log
+
+import "os"
+
+// isTerminalFd reports whether f is open on a character device,
+// which is as close to "a terminal" as we can get here without
+// platform-specific calls.
+func isTerminalFd(f *os.File, fd uintptr) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
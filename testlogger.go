@@ -0,0 +1,164 @@
+package log
+
+import "fmt"
+import "sync"
+
+// An Entry is a single call recorded by a TestLogger.
+type Entry struct {
+	Level  Level
+	Prefix string
+	Format string
+	Args   []interface{}
+	Fields map[string]interface{}
+}
+
+// Message returns the formatted message of the entry.
+func (e Entry) Message() string {
+	return fmt.Sprintf(e.Format, e.Args...)
+}
+
+// A TestLogger records every call made to it instead of writing
+// anything, for tests to make assertions about what was logged.
+// Loggers derived via Prefix and With record into the same list.
+type TestLogger struct {
+	rec    *recording
+	prefix string
+	fields []field
+}
+
+// recording is the list of entries shared by a tree of TestLoggers.
+type recording struct {
+	mu      sync.Mutex
+	entries []Entry
+	noPanic bool
+}
+
+// NewTest returns a TestLogger with nothing recorded.
+func NewTest() *TestLogger {
+	return &TestLogger{rec: &recording{}}
+}
+
+// Messages returns a copy of the entries recorded so far, in order.
+func (t *TestLogger) Messages() []Entry {
+	t.rec.mu.Lock()
+	defer t.rec.mu.Unlock()
+	entries := make([]Entry, len(t.rec.entries))
+	copy(entries, t.rec.entries)
+	return entries
+}
+
+// SetPanic controls whether Panic panics and Fatal exits after
+// recording, which they do by default. Disabling it lets a test make
+// its assertions without recovering. The setting is shared by every
+// logger derived from the same NewTest.
+func (t *TestLogger) SetPanic(enabled bool) {
+	t.rec.mu.Lock()
+	defer t.rec.mu.Unlock()
+	t.rec.noPanic = !enabled
+}
+
+// record appends an entry, and reports whether Panic and Fatal
+// should go on to panic or exit.
+func (t *TestLogger) record(level Level, format string, v []interface{}) bool {
+	e := Entry{
+		Level:  level,
+		Prefix: t.prefix,
+		Format: format,
+		Args:   v,
+	}
+	if len(t.fields) > 0 {
+		e.Fields = make(map[string]interface{}, len(t.fields))
+		for _, f := range t.fields {
+			e.Fields[f.key] = f.value
+		}
+	}
+
+	t.rec.mu.Lock()
+	defer t.rec.mu.Unlock()
+	t.rec.entries = append(t.rec.entries, e)
+	return !t.rec.noPanic
+}
+
+// Log records the call. At PanicLevel it then panics, and at
+// FatalLevel or above it exits, unless disabled with SetPanic.
+func (t *TestLogger) Log(level Level, format string, v ...interface{}) {
+	if !t.record(level, format, v) {
+		return
+	}
+	switch {
+	case level >= FatalLevel:
+		osExit(1)
+	case level == PanicLevel:
+		panic(fmt.Sprintf(format, v...))
+	}
+}
+
+// Debug records the call.
+func (t *TestLogger) Debug(format string, v ...interface{}) {
+	t.Log(DebugLevel, format, v...)
+}
+
+// Info records the call.
+func (t *TestLogger) Info(format string, v ...interface{}) {
+	t.Log(InfoLevel, format, v...)
+}
+
+// Warning records the call.
+func (t *TestLogger) Warning(format string, v ...interface{}) {
+	t.Log(WarningLevel, format, v...)
+}
+
+// Error records the call.
+func (t *TestLogger) Error(format string, v ...interface{}) {
+	t.Log(ErrorLevel, format, v...)
+}
+
+// Panic records the call, and then panics with the formatted
+// message unless disabled with SetPanic.
+func (t *TestLogger) Panic(format string, v ...interface{}) {
+	t.Log(PanicLevel, format, v...)
+}
+
+// Fatal records the call, and then exits the process with status 1
+// unless disabled with SetPanic.
+func (t *TestLogger) Fatal(format string, v ...interface{}) {
+	t.Log(FatalLevel, format, v...)
+}
+
+// Enabled reports true, since t records every call.
+func (t *TestLogger) Enabled(level Level) bool {
+	return true
+}
+
+// Must calls t.Panic() if err is not nil.
+func (t *TestLogger) Must(message string, err error) {
+	if err != nil {
+		t.Panic("Failed to %s: %v", message, err)
+	}
+}
+
+// CurrentPrefix returns the prefix accumulated by Prefix calls so
+// far.
+func (t *TestLogger) CurrentPrefix() string {
+	return t.prefix
+}
+
+// Prefix returns a new TestLogger with this prefix appended, which
+// records into the same list as t.
+func (t *TestLogger) Prefix(prefix string) Logger {
+	nt := *t
+	if t.prefix == "" {
+		nt.prefix = prefix
+	} else {
+		nt.prefix = t.prefix + ":" + prefix
+	}
+	return &nt
+}
+
+// With returns a new TestLogger that records key=value in the Fields
+// of each entry, and records into the same list as t.
+func (t *TestLogger) With(key string, value interface{}) Logger {
+	nt := *t
+	nt.fields = append(t.fields[:len(t.fields):len(t.fields)], field{key: key, value: value})
+	return &nt
+}
@@ -0,0 +1,88 @@
+package log
+
+import "fmt"
+import "sync"
+import "testing"
+
+func TestTestLogger(t *testing.T) {
+	tl := NewTest()
+	tl.Info("hello %s", "world")
+	tl.Prefix("db").With("rows", 3).Warning("slow query")
+
+	got := tl.Messages()
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+	tests := []struct {
+		e       Entry
+		level   Level
+		prefix  string
+		format  string
+		message string
+		fields  map[string]interface{}
+	}{
+		{got[0], InfoLevel, "", "hello %s", "hello world", nil},
+		{got[1], WarningLevel, "db", "slow query", "slow query", map[string]interface{}{"rows": 3}},
+	}
+	for i, tt := range tests {
+		if tt.e.Level != tt.level || tt.e.Prefix != tt.prefix || tt.e.Format != tt.format {
+			t.Errorf("entry %d = %+v", i, tt.e)
+		}
+		if m := tt.e.Message(); m != tt.message {
+			t.Errorf("entry %d message = %q, want %q", i, m, tt.message)
+		}
+		if fmt.Sprint(tt.e.Fields) != fmt.Sprint(tt.fields) {
+			t.Errorf("entry %d fields = %v, want %v", i, tt.e.Fields, tt.fields)
+		}
+	}
+}
+
+func TestTestLoggerPanic(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		want    interface{}
+		code    int
+	}{
+		{"enabled", true, "boom 1", 1},
+		{"disabled", false, nil, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := stubExit(t)
+			tl := NewTest()
+			tl.SetPanic(tt.enabled)
+			// the setting is shared with derived loggers
+			pl := tl.Prefix("p")
+			if v := catchPanic(func() { pl.Panic("boom %d", 1) }); v != tt.want {
+				t.Errorf("panicked with %v, want %v", v, tt.want)
+			}
+			pl.Fatal("the end")
+			if *code != tt.code {
+				t.Errorf("exited with %d, want %d", *code, tt.code)
+			}
+			if n := len(tl.Messages()); n != 2 {
+				t.Errorf("recorded %d entries, want 2", n)
+			}
+		})
+	}
+}
+
+func TestTestLoggerConcurrent(t *testing.T) {
+	const goroutines, lines = 8, 50
+	tl := NewTest()
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(l Logger) {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				l.Info("line %d", i)
+			}
+		}(tl.Prefix(fmt.Sprint("g", g)))
+	}
+	wg.Wait()
+	if n := len(tl.Messages()); n != goroutines*lines {
+		t.Errorf("recorded %d entries, want %d", n, goroutines*lines)
+	}
+}
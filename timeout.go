@@ -0,0 +1,93 @@
+package log
+
+import "io"
+import "fmt"
+import "sync"
+import "time"
+
+// A TimeoutWriter gives up on writes to another writer that don't
+// finish in time, such as to a pipe whose reader has stalled, so
+// that a stuck sink can't wedge the program. It is safe for
+// concurrent use.
+//
+// Go can't cancel a blocked write, so each write runs in a goroutine
+// of its own, and one that times out is left running until the
+// underlying writer returns. Until it does, later lines are dropped
+// straight away rather than queueing up more goroutines behind it.
+// Each line also costs a copy and a goroutine handoff, so only wrap
+// writers that are actually at risk of blocking.
+type TimeoutWriter struct {
+	w        io.Writer
+	d        time.Duration
+	fallback io.Writer
+
+	mu    sync.Mutex
+	stuck chan struct{}
+}
+
+// NewTimeoutWriter returns a writer that writes to w, dropping any
+// line that takes longer than d. For each dropped line it writes a
+// warning to fallback, which may be nil to drop lines silently. A d
+// of zero or less disables the timeout, so that every write goes
+// straight to w.
+func NewTimeoutWriter(w io.Writer, d time.Duration, fallback io.Writer) *TimeoutWriter {
+	return &TimeoutWriter{w: w, d: d, fallback: fallback}
+}
+
+// Write writes p to the underlying writer, waiting for at most the
+// timeout. A dropped line is reported as written, so that the logger
+// carries on rather than treating the stall as a write failure.
+func (t *TimeoutWriter) Write(p []byte) (int, error) {
+	if t.d <= 0 {
+		return t.w.Write(p)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stuck != nil {
+		select {
+		case <-t.stuck:
+			t.stuck = nil
+		default:
+			t.drop(p)
+			return len(p), nil
+		}
+	}
+
+	// the write may outlive this call, and p belongs to the caller
+	line := append([]byte(nil), p...)
+	done := make(chan struct{})
+	var n int
+	var err error
+	go func() {
+		n, err = t.w.Write(line)
+		close(done)
+	}()
+
+	timer := time.NewTimer(t.d)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return n, err
+	case <-timer.C:
+		t.stuck = done
+		t.drop(p)
+		return len(p), nil
+	}
+}
+
+// drop warns on the fallback writer that p was dropped.
+func (t *TimeoutWriter) drop(p []byte) {
+	if t.fallback == nil {
+		return
+	}
+	fmt.Fprintf(t.fallback, "log: write timed out after %v, dropped: %s", t.d, p)
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (t *TimeoutWriter) Close() error {
+	if c, ok := t.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package log
+
+import "time"
+import "errors"
+import "testing"
+import "strings"
+
+func TestTimeoutWriter(t *testing.T) {
+	under := newGateWriter()
+	fallback := &CaptureBuffer{}
+	w := NewTimeoutWriter(under, 10*time.Millisecond, fallback)
+
+	start := time.Now()
+	if n, err := w.Write([]byte("one\n")); n != 4 || err != nil {
+		t.Errorf("Write = %d, %v, want the dropped line reported as written", n, err)
+	}
+	if d := time.Since(start); d < 10*time.Millisecond {
+		t.Errorf("gave up after %v, before the timeout", d)
+	}
+	// while the first write is stuck, lines are dropped at once
+	w.Write([]byte("two\n"))
+
+	want := "log: write timed out after 10ms, dropped: one\n" +
+		"log: write timed out after 10ms, dropped: two\n"
+	if got := fallback.String(); got != want {
+		t.Errorf("fallback got %q, want %q", got, want)
+	}
+
+	close(under.released)
+	// once the stuck write returns, writing resumes
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(under.String(), "one\n") {
+		if time.Now().After(deadline) {
+			t.Fatal("stuck write never finished")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := w.Write([]byte("three\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := under.String(); got != "one\nthree\n" {
+		t.Errorf("got %q, want one and three", got)
+	}
+}
+
+func TestTimeoutWriterDisabled(t *testing.T) {
+	under := &CaptureBuffer{}
+	w := NewTimeoutWriter(under, 0, nil)
+	if _, err := w.Write([]byte("one\n")); err != nil || under.String() != "one\n" {
+		t.Errorf("got %q, %v", under.String(), err)
+	}
+}
+
+func TestTimeoutWriterPassesErrors(t *testing.T) {
+	errBroken := errors.New("broken pipe")
+	w := NewTimeoutWriter(errWriter{errBroken}, time.Second, nil)
+	if _, err := w.Write([]byte("one\n")); err != errBroken {
+		t.Errorf("got error %v, want the writer's", err)
+	}
+}
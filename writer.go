@@ -0,0 +1,52 @@
+package log
+
+import "io"
+import "strings"
+
+// levelWriter turns writes into log lines at a fixed level.
+type levelWriter struct {
+	l     *DefaultLogger
+	level Level
+}
+
+// WriterAt returns an io.Writer that logs each line written to it at
+// level, so that code writing to an io.Writer or a standard library
+// *log.Logger can feed into l:
+//
+//	stdlog.SetOutput(l.WriterAt(InfoLevel))
+//
+// A trailing newline is trimmed rather than logged as an empty line,
+// and an empty write, or one of a lone newline, logs nothing.
+// Writing at PanicLevel or FatalLevel panics or exits after the
+// first line, just as calling Panic or Fatal would.
+func (l *DefaultLogger) WriterAt(level Level) io.Writer {
+	return &levelWriter{l: l, level: level}
+}
+
+// Write logs each line in p, and always reports writing all of p.
+func (w *levelWriter) Write(p []byte) (int, error) {
+	s := strings.TrimSuffix(string(p), "\n")
+	if s == "" {
+		return len(p), nil
+	}
+	for _, line := range strings.Split(s, "\n") {
+		w.l.Log(w.level, "%s", line)
+	}
+	return len(p), nil
+}
+
+// Func returns a Printf-style function that logs at level, for
+// libraries that take a logging function rather than a Logger:
+//
+//	client.SetLogf(l.Func(DebugLevel))
+func (l *DefaultLogger) Func(level Level) func(format string, v ...interface{}) {
+	return func(format string, v ...interface{}) {
+		l.Log(level, format, v...)
+	}
+}
+
+// Printf logs at InfoLevel, so that l satisfies interfaces with a
+// Printf method, such as that of many libraries' logger options.
+func (l *DefaultLogger) Printf(format string, v ...interface{}) {
+	l.Log(InfoLevel, format, v...)
+}
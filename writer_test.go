@@ -0,0 +1,98 @@
+package log
+
+import "testing"
+import "strings"
+import stdlog "log"
+
+func TestWriterAt(t *testing.T) {
+	tests := []struct {
+		name  string
+		write []byte
+		want  []string
+	}{
+		{"nil", nil, nil},
+		{"empty", []byte(""), nil},
+		{"lone newline", []byte("\n"), nil},
+		{"one line", []byte("hello\n"), []string{"hello"}},
+		{"no newline", []byte("hello"), []string{"hello"}},
+		{"two lines", []byte("one\ntwo\n"), []string{"one", "two"}},
+		{"blank line inside", []byte("one\n\ntwo\n"), []string{"one", "", "two"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newLogger()
+			n, err := l.Prefix("lib").(*DefaultLogger).WriterAt(WarningLevel).Write(tt.write)
+			if n != len(tt.write) || err != nil {
+				t.Errorf("Write = %d, %v, want %d, nil", n, err, len(tt.write))
+			}
+			var want []string
+			for _, msg := range tt.want {
+				want = append(want, "2006-01-02T15:04:05Z\tWARN\tlib\t"+msg)
+			}
+			if got := buf.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestWriterAtStdlog(t *testing.T) {
+	l, buf := newLogger()
+	std := stdlog.New(l.WriterAt(InfoLevel), "", 0)
+	std.Printf("hello %d", 1)
+	std.Print("")
+	if want := "2006-01-02T15:04:05Z\tINFO\t\thello 1\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriterAtPanics(t *testing.T) {
+	l, buf := newLogger()
+	v := catchPanic(func() { l.WriterAt(PanicLevel).Write([]byte("one\ntwo\n")) })
+	if v != "one" {
+		t.Errorf("panicked with %v, want one", v)
+	}
+	if len(buf.Lines()) != 1 {
+		t.Errorf("got %q, want only the first line", buf.Lines())
+	}
+}
+
+// library stands in for a library taking a logging function.
+func library(logf func(format string, v ...interface{})) {
+	logf("connected to %s", "db1")
+}
+
+func TestFunc(t *testing.T) {
+	tests := []struct {
+		level Level
+		want  string
+	}{
+		{DebugLevel, "2006-01-02T15:04:05Z\tDEBUG\tp\tconnected to db1\n"},
+		{InfoLevel, "2006-01-02T15:04:05Z\tINFO\tp\tconnected to db1\n"},
+		{ErrorLevel, "2006-01-02T15:04:05Z\tERROR\tp\tconnected to db1\n"},
+		{TraceLevel, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			l, buf := newLogger()
+			l.SetDebug(true)
+			library(l.Prefix("p").(*DefaultLogger).Func(tt.level))
+			if buf.String() != tt.want {
+				t.Errorf("got %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintf(t *testing.T) {
+	l, buf := newLogger()
+	var p interface {
+		Printf(format string, v ...interface{})
+	} = l
+	p.Printf("hello %d", 1)
+	library(l.Printf)
+	want := "2006-01-02T15:04:05Z\tINFO\t\thello 1\n2006-01-02T15:04:05Z\tINFO\t\tconnected to db1\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}